@@ -0,0 +1,57 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/cakturk/pkg/wavdiff"
+)
+
+var (
+	tolerance = flag.Float64("tolerance", 0, "largest sample difference, relative to full scale, treated as equal")
+	noMeta    = flag.Bool("nometa", false, "ignore LIST/INFO and cue chunks")
+)
+
+func run() (bool, error) {
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [flags] a.wav b.wav\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	a, err := os.Open(flag.Arg(0))
+	if err != nil {
+		return false, err
+	}
+	defer a.Close()
+	b, err := os.Open(flag.Arg(1))
+	if err != nil {
+		return false, err
+	}
+	defer b.Close()
+	r, err := wavdiff.Compare(a, b, &wavdiff.Options{
+		Tolerance:      *tolerance,
+		IgnoreMetadata: *noMeta,
+	})
+	if err != nil {
+		return false, err
+	}
+	fmt.Println(r)
+	return r.Equal(), nil
+}
+
+func main() {
+	log.SetFlags(log.Lshortfile)
+	equal, err := run()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if !equal {
+		os.Exit(1)
+	}
+}
@@ -1,7 +1,9 @@
 package main
 
 import (
+	"errors"
 	"flag"
+	"fmt"
 	"log"
 	"os"
 
@@ -11,13 +13,53 @@ import (
 var (
 	start   = flag.Duration("from", 0, "indicates the starting point")
 	end     = flag.Duration("to", -1, "indicates when to stop")
+	rng     = flag.String("range", "", "trim to the range `EXPR` instead of -from and -to, e.g. -30s:, 10s+5s or 25%:75%")
+	pad     = flag.String("pad", "", "pad a range past the end of the input with silence at the `start` or end")
 	inFile  = flag.String("i", "", "input wav `FILE`: '-' implies stdin")
 	outFile = flag.String("o", "", "put newly cropped samples into this `FILE`: '-' implies stdout")
 )
 
+// rangeFlags checks the -range and -pad flags, given as rng and pad, and
+// parses them. fromTo tells whether -from or -to was set.
+func rangeFlags(rng, pad string, fromTo bool) (wavtrimmer.RangeExpr, wavtrimmer.TrimOptions, error) {
+	var (
+		expr wavtrimmer.RangeExpr
+		opts wavtrimmer.TrimOptions
+		err  error
+	)
+	if rng != "" {
+		if fromTo {
+			return expr, opts, errors.New("-range cannot be used with -from or -to")
+		}
+		if expr, err = wavtrimmer.ParseRange(rng); err != nil {
+			return expr, opts, err
+		}
+	}
+	switch pad {
+	case "":
+	case "start":
+		opts.Pad = wavtrimmer.PadStart
+	case "end":
+		opts.Pad = wavtrimmer.PadEnd
+	default:
+		return expr, opts, fmt.Errorf("-pad must be start or end, not %q", pad)
+	}
+	if pad != "" && rng == "" {
+		return expr, opts, errors.New("-pad requires -range")
+	}
+	return expr, opts, nil
+}
+
 func run() error {
-	var err error
 	flag.Parse()
+	var fromTo bool
+	flag.Visit(func(f *flag.Flag) {
+		fromTo = fromTo || f.Name == "from" || f.Name == "to"
+	})
+	expr, opts, err := rangeFlags(*rng, *pad, fromTo)
+	if err != nil {
+		return err
+	}
 	inf := os.Stdin
 	if *inFile != "" && *inFile != "-" {
 		inf, err = os.Open(*inFile)
@@ -34,7 +76,17 @@ func run() error {
 		}
 	}
 	defer ouf.Close()
-	return wavtrimmer.Trim2(inf, *start, *end, ouf)
+	if *rng == "" {
+		return wavtrimmer.Trim2(inf, *start, *end, ouf)
+	}
+	padding, err := wavtrimmer.TrimRange(inf, expr, ouf, &opts)
+	if err != nil {
+		return err
+	}
+	if n := padding.Frames(); n > 0 {
+		log.Printf("padded with %d frames of silence", n)
+	}
+	return nil
 }
 
 func main() {
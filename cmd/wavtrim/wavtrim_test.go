@@ -0,0 +1,42 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/cakturk/pkg/wavtrimmer"
+)
+
+func TestRangeFlags(t *testing.T) {
+	tests := []struct {
+		rng, pad string
+		fromTo   bool
+		err      string
+		mode     wavtrimmer.PadMode
+	}{
+		{"", "", true, "", wavtrimmer.NoPad},
+		{"-30s:", "", false, "", wavtrimmer.NoPad},
+		{"10s+5s", "start", false, "", wavtrimmer.PadStart},
+		{"10s+5s", "end", false, "", wavtrimmer.PadEnd},
+		{"-30s:", "", true, "-range cannot be used with -from or -to", 0},
+		{"10s+5s", "both", false, `-pad must be start or end, not "both"`, 0},
+		{"", "end", false, "-pad requires -range", 0},
+		{"", "end", true, "-pad requires -range", 0},
+		{"10s", "", false, `trim: invalid range "10s": want START:END or START+LENGTH`, 0},
+	}
+	for _, tt := range tests {
+		expr, opts, err := rangeFlags(tt.rng, tt.pad, tt.fromTo)
+		if tt.err != "" {
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("-range %q -pad %q: got error %v, want %q", tt.rng, tt.pad, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("-range %q -pad %q: %v", tt.rng, tt.pad, err)
+			continue
+		}
+		if expr.String() != tt.rng || opts.Pad != tt.mode {
+			t.Errorf("-range %q -pad %q: got %q, pad %d", tt.rng, tt.pad, expr, opts.Pad)
+		}
+	}
+}
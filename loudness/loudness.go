@@ -0,0 +1,402 @@
+// Package loudness implements level and loudness measurements of wav files
+// as described in ITU-R BS.1770-4 and EBU R128 / Tech 3342.
+package loudness
+
+import (
+	"encoding/json"
+	"io"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/cakturk/pkg/wav"
+)
+
+// DB is a level in decibels. Silence is reported as negative infinity, which
+// is encoded as null in JSON.
+type DB float64
+
+func (d DB) MarshalJSON() ([]byte, error) {
+	if math.IsInf(float64(d), 0) || math.IsNaN(float64(d)) {
+		return []byte("null"), nil
+	}
+	return json.Marshal(math.Round(float64(d)*100) / 100)
+}
+
+func (d *DB) UnmarshalJSON(b []byte) error {
+	if string(b) == "null" {
+		*d = DB(math.Inf(-1))
+		return nil
+	}
+	return json.Unmarshal(b, (*float64)(d))
+}
+
+// ChannelStats holds the level measurements of a single channel.
+type ChannelStats struct {
+	Peak     DB      `json:"peak"`      // sample peak, dBFS
+	TruePeak DB      `json:"true_peak"` // 4x oversampled peak, dBTP
+	RMS      DB      `json:"rms"`       // dBFS, a full scale sine is -3 dBFS
+	DCOffset float64 `json:"dc_offset"` // mean sample value
+}
+
+// Result holds the measurements of a whole file.
+type Result struct {
+	Channels []ChannelStats `json:"channels"`
+	Peak     DB             `json:"peak"`      // dBFS
+	TruePeak DB             `json:"true_peak"` // dBTP
+
+	Integrated   DB `json:"integrated"`     // LUFS
+	Range        DB `json:"range"`          // LU
+	MaxMomentary DB `json:"max_momentary"`  // LUFS
+	MaxShortTerm DB `json:"max_short_term"` // LUFS
+
+	Frames   int64         `json:"frames"`
+	Duration time.Duration `json:"duration"`
+}
+
+const (
+	absoluteGate  = -70 // LUFS
+	relativeGate  = -10 // LU, integrated loudness
+	rangeGate     = -20 // LU, loudness range
+	momentaryLen  = 4   // 400ms, in 100ms sub-blocks
+	shortTermLen  = 30  // 3s, in 100ms sub-blocks
+	oversampling  = 4
+	truePeakTaps  = 48
+	truePeakPhase = truePeakTaps / oversampling
+)
+
+// Analyzer measures the frames written into it.
+type Analyzer struct {
+	nchans     int
+	sampleRate int
+	weights    []float64
+
+	frames   int64
+	peak     []float64
+	sum      []float64
+	sumSq    []float64
+	filters  []kFilter
+	tpeak    []float64
+	tphist   [][]float64 // last truePeakPhase samples of each channel
+	tpfir    [oversampling][truePeakPhase]float64
+	blockLen int       // frames in a 100ms sub-block
+	blockPos int       // frames in the current sub-block
+	blockSq  []float64 // K-weighted sum of squares of the current sub-block
+	blocks   []float64 // weighted mean square of each finished sub-block
+
+	momentary []float64
+	shortTerm []float64
+}
+
+// NewAnalyzer returns an Analyzer for frames in the format f.
+func NewAnalyzer(f *wav.FmtChunk) *Analyzer {
+	n := int(f.NumChans)
+	a := &Analyzer{
+		nchans:     n,
+		sampleRate: int(f.SampleRate),
+		weights:    channelWeights(f),
+		peak:       make([]float64, n),
+		sum:        make([]float64, n),
+		sumSq:      make([]float64, n),
+		filters:    make([]kFilter, n),
+		tpeak:      make([]float64, n),
+		tphist:     make([][]float64, n),
+		blockLen:   int(math.Round(float64(f.SampleRate) / 10)),
+		blockSq:    make([]float64, n),
+	}
+	for c := range a.filters {
+		a.filters[c] = newKFilter(float64(f.SampleRate))
+		a.tphist[c] = make([]float64, truePeakPhase)
+	}
+	a.tpfir = interpolator()
+	return a
+}
+
+// Analyze reads every frame of wf and returns its measurements.
+func Analyze(wf *wav.WavFile) (*Result, error) {
+	if r := wf.Data.PCMReader(); r != nil {
+		if _, err := r.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+	}
+	fr, err := wf.FrameReader()
+	if err != nil {
+		return nil, err
+	}
+	a := NewAnalyzer(&wf.Fmt)
+	p := make([]float64, 4096*int(wf.Fmt.NumChans))
+	for {
+		n, err := fr.ReadFrames(p)
+		a.WriteFrames(p[:n*int(wf.Fmt.NumChans)])
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return a.Result(), nil
+}
+
+// WriteFrames measures the interleaved frames in p.
+func (a *Analyzer) WriteFrames(p []float64) {
+	for i := 0; i+a.nchans <= len(p); i += a.nchans {
+		for c, s := range p[i : i+a.nchans] {
+			if v := math.Abs(s); v > a.peak[c] {
+				a.peak[c] = v
+			}
+			a.sum[c] += s
+			a.sumSq[c] += s * s
+			a.truePeak(c, s)
+			k := a.filters[c].process(s)
+			a.blockSq[c] += k * k
+		}
+		a.frames++
+		if a.blockPos++; a.blockPos == a.blockLen {
+			a.endBlock()
+		}
+	}
+}
+
+func (a *Analyzer) truePeak(c int, s float64) {
+	h := a.tphist[c]
+	copy(h[1:], h[:len(h)-1])
+	h[0] = s
+	for _, phase := range a.tpfir {
+		var y float64
+		for j, g := range phase {
+			y += g * h[j]
+		}
+		if y = math.Abs(y); y > a.tpeak[c] {
+			a.tpeak[c] = y
+		}
+	}
+}
+
+func (a *Analyzer) endBlock() {
+	var ms float64
+	for c, sq := range a.blockSq {
+		ms += a.weights[c] * sq / float64(a.blockLen)
+		a.blockSq[c] = 0
+	}
+	a.blockPos = 0
+	a.blocks = append(a.blocks, ms)
+	if n := len(a.blocks); n >= momentaryLen {
+		a.momentary = append(a.momentary, mean(a.blocks[n-momentaryLen:]))
+	}
+	if n := len(a.blocks); n >= shortTermLen {
+		a.shortTerm = append(a.shortTerm, mean(a.blocks[n-shortTermLen:]))
+	}
+}
+
+// Result returns the measurements of the frames written so far.
+func (a *Analyzer) Result() *Result {
+	r := &Result{
+		Channels:     make([]ChannelStats, a.nchans),
+		Peak:         DB(math.Inf(-1)),
+		TruePeak:     DB(math.Inf(-1)),
+		Frames:       a.frames,
+		Integrated:   DB(math.Inf(-1)),
+		Range:        0,
+		MaxMomentary: DB(math.Inf(-1)),
+		MaxShortTerm: DB(math.Inf(-1)),
+	}
+	if a.sampleRate > 0 {
+		r.Duration = time.Duration(a.frames) * time.Second / time.Duration(a.sampleRate)
+	}
+	for c := range r.Channels {
+		cs := &r.Channels[c]
+		// the interpolator delays its output; the samples still in
+		// flight can only be original samples which peak covers
+		cs.Peak = toDB(a.peak[c])
+		cs.TruePeak = toDB(math.Max(a.tpeak[c], a.peak[c]))
+		if a.frames > 0 {
+			cs.RMS = toDB(math.Sqrt(a.sumSq[c] / float64(a.frames)))
+			cs.DCOffset = a.sum[c] / float64(a.frames)
+		} else {
+			cs.RMS = DB(math.Inf(-1))
+		}
+		r.Peak = max(r.Peak, cs.Peak)
+		r.TruePeak = max(r.TruePeak, cs.TruePeak)
+	}
+	for _, ms := range a.momentary {
+		r.MaxMomentary = max(r.MaxMomentary, lufs(ms))
+	}
+	for _, ms := range a.shortTerm {
+		r.MaxShortTerm = max(r.MaxShortTerm, lufs(ms))
+	}
+	r.Integrated = integrated(a.momentary)
+	r.Range = loudnessRange(a.shortTerm)
+	return r
+}
+
+// integrated returns the gated loudness of the momentary blocks.
+func integrated(blocks []float64) DB {
+	gated := gate(blocks, absoluteGate)
+	if len(gated) == 0 {
+		return DB(math.Inf(-1))
+	}
+	gated = gate(gated, float64(lufs(mean(gated)))+relativeGate)
+	if len(gated) == 0 {
+		return DB(math.Inf(-1))
+	}
+	return lufs(mean(gated))
+}
+
+// loudnessRange returns the LRA of the short-term blocks as defined in EBU
+// Tech 3342.
+func loudnessRange(blocks []float64) DB {
+	gated := gate(blocks, absoluteGate)
+	if len(gated) == 0 {
+		return 0
+	}
+	gated = gate(gated, float64(lufs(mean(gated)))+rangeGate)
+	if len(gated) == 0 {
+		return 0
+	}
+	l := make([]float64, len(gated))
+	for i, ms := range gated {
+		l[i] = float64(lufs(ms))
+	}
+	sort.Float64s(l)
+	return DB(percentile(l, 0.95) - percentile(l, 0.10))
+}
+
+func gate(blocks []float64, threshold float64) []float64 {
+	var out []float64
+	for _, ms := range blocks {
+		if float64(lufs(ms)) > threshold {
+			out = append(out, ms)
+		}
+	}
+	return out
+}
+
+func percentile(sorted []float64, p float64) float64 {
+	i := int(math.Round(p * float64(len(sorted)-1)))
+	return sorted[i]
+}
+
+func mean(p []float64) float64 {
+	var s float64
+	for _, v := range p {
+		s += v
+	}
+	return s / float64(len(p))
+}
+
+func lufs(ms float64) DB {
+	return DB(-0.691 + 10*math.Log10(ms))
+}
+
+func toDB(v float64) DB {
+	return DB(20 * math.Log10(v))
+}
+
+// channelWeights returns the BS.1770 weights of each channel: surround
+// channels are weighted by 1.41 and the LFE channel is not measured.
+func channelWeights(f *wav.FmtChunk) []float64 {
+	const (
+		lfe       = 0x8
+		surrounds = 0x10 | 0x20 | 0x200 | 0x400 // back and side left/right
+	)
+	n := int(f.NumChans)
+	w := make([]float64, n)
+	for c := range w {
+		w[c] = 1
+	}
+	mask := f.ChannelMask
+	if mask == 0 && n == 6 {
+		mask = 0x3f // assume 5.1: L R C LFE Ls Rs
+	}
+	c := 0
+	for bit := uint32(1); bit != 0 && c < n; bit <<= 1 {
+		if mask&bit == 0 {
+			continue
+		}
+		switch {
+		case bit == lfe:
+			w[c] = 0
+		case bit&surrounds != 0:
+			w[c] = 1.41
+		}
+		c++
+	}
+	return w
+}
+
+// kFilter is the K-weighting pre-filter of BS.1770: a high shelf followed by
+// a high pass filter.
+type kFilter struct {
+	shelf, hp biquad
+}
+
+func newKFilter(fs float64) kFilter {
+	var kf kFilter
+
+	f0, g, q := 1681.974450955533, 3.999843853973347, 0.7071752369554196
+	k := math.Tan(math.Pi * f0 / fs)
+	vh := math.Pow(10, g/20)
+	vb := math.Pow(vh, 0.4996667741545416)
+	a0 := 1 + k/q + k*k
+	kf.shelf = biquad{
+		b0: (vh + vb*k/q + k*k) / a0,
+		b1: 2 * (k*k - vh) / a0,
+		b2: (vh - vb*k/q + k*k) / a0,
+		a1: 2 * (k*k - 1) / a0,
+		a2: (1 - k/q + k*k) / a0,
+	}
+
+	f0, q = 38.13547087602444, 0.5003270373238773
+	k = math.Tan(math.Pi * f0 / fs)
+	a0 = 1 + k/q + k*k
+	kf.hp = biquad{
+		b0: 1, b1: -2, b2: 1,
+		a1: 2 * (k*k - 1) / a0,
+		a2: (1 - k/q + k*k) / a0,
+	}
+	return kf
+}
+
+func (kf *kFilter) process(x float64) float64 {
+	return kf.hp.process(kf.shelf.process(x))
+}
+
+// biquad is a direct form II transposed biquad filter.
+type biquad struct {
+	b0, b1, b2, a1, a2 float64
+	z1, z2             float64
+}
+
+func (b *biquad) process(x float64) float64 {
+	y := b.b0*x + b.z1
+	b.z1 = b.b1*x - b.a1*y + b.z2
+	b.z2 = b.b2*x - b.a2*y
+	return y
+}
+
+// interpolator returns the polyphase coefficients of a 48 tap Blackman
+// windowed sinc filter used for 4x oversampling. Each phase is normalized to
+// unity gain at DC.
+func interpolator() [oversampling][truePeakPhase]float64 {
+	var fir [oversampling][truePeakPhase]float64
+	for p := 0; p < oversampling; p++ {
+		var sum float64
+		for j := 0; j < truePeakPhase; j++ {
+			k := j*oversampling + p
+			t := float64(truePeakTaps/2-k) / oversampling
+			x := 2 * math.Pi * float64(k) / truePeakTaps
+			w := 0.42 - 0.5*math.Cos(x) + 0.08*math.Cos(2*x)
+			h := w
+			if t != 0 {
+				h *= math.Sin(math.Pi*t) / (math.Pi * t)
+			}
+			fir[p][j] = h
+			sum += h
+		}
+		for j := range fir[p] {
+			fir[p][j] /= sum
+		}
+	}
+	return fir
+}
@@ -0,0 +1,125 @@
+package loudness
+
+import (
+	"encoding/json"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cakturk/pkg/wav"
+	"github.com/cakturk/pkg/wavgen"
+)
+
+// sineWav writes a stereo 48kHz 24-bit wav file holding a sine wave of the
+// given frequency whose amplitude changes at each segment.
+func sineWav(t *testing.T, freq, phase float64, segs ...segment) *wav.WavFile {
+	t.Helper()
+	f, err := os.Create(filepath.Join(t.TempDir(), "sine.wav"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+	var amps []float64 // amplitude of each frame
+	for _, s := range segs {
+		amp := math.Pow(10, s.dbfs/20)
+		for i := 0; i < int(s.seconds*48000); i++ {
+			amps = append(amps, amp)
+		}
+	}
+	sine := wavgen.SignalFunc(func(n int64, rate int) float64 {
+		return amps[n] * math.Sin(2*math.Pi*freq*float64(n)/float64(rate)+phase)
+	})
+	format := wavgen.Format{SampleRate: 48000, Channels: 2, Bits: 24}
+	if err := wavgen.Write(f, format, int64(len(amps)), sine); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	wf, err := wav.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return wf
+}
+
+type segment struct {
+	dbfs    float64
+	seconds float64
+}
+
+func near(got DB, want, tolerance float64) bool {
+	return math.Abs(float64(got)-want) <= tolerance
+}
+
+// EBU Tech 3341, test case 1: a stereo 1kHz sine at -23 dBFS measures
+// -23 LUFS.
+func TestIntegrated(t *testing.T) {
+	r, err := Analyze(sineWav(t, 1000, 0, segment{-23, 20}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !near(r.Integrated, -23, 0.1) {
+		t.Errorf("Integrated = %.2f, want -23", r.Integrated)
+	}
+	if !near(r.MaxMomentary, -23, 0.1) || !near(r.MaxShortTerm, -23, 0.1) {
+		t.Errorf("MaxMomentary = %.2f, MaxShortTerm = %.2f, want -23",
+			r.MaxMomentary, r.MaxShortTerm)
+	}
+	for _, c := range r.Channels {
+		if !near(c.Peak, -23, 0.01) || !near(c.RMS, -26.01, 0.01) ||
+			math.Abs(c.DCOffset) > 1e-4 {
+			t.Errorf("unexpected channel stats: %+v", c)
+		}
+	}
+}
+
+// EBU Tech 3342, test case 1: 20s at -20 dBFS followed by 20s at -30 dBFS
+// has a loudness range of 10 LU.
+func TestRange(t *testing.T) {
+	r, err := Analyze(sineWav(t, 1000, 0, segment{-20, 20}, segment{-30, 20}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !near(r.Range, 10, 1) {
+		t.Errorf("Range = %.2f, want 10", r.Range)
+	}
+}
+
+func TestTruePeak(t *testing.T) {
+	// a quarter sample rate sine sampled at 45 degrees peaks 3 dB above
+	// its samples
+	r, err := Analyze(sineWav(t, 12000, math.Pi/4, segment{-6, 1}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !near(r.Peak, -9.01, 0.01) {
+		t.Errorf("Peak = %.2f, want -9.01", r.Peak)
+	}
+	if !near(r.TruePeak, -6, 0.2) {
+		t.Errorf("TruePeak = %.2f, want -6", r.TruePeak)
+	}
+}
+
+func TestSilenceJSON(t *testing.T) {
+	r, err := Analyze(sineWav(t, 1000, 0, segment{math.Inf(-1), 1}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got Result
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !math.IsInf(float64(got.Integrated), -1) || !math.IsInf(float64(got.Channels[1].Peak), -1) {
+		t.Errorf("silence did not round trip: %s", b)
+	}
+	if got.Frames != 48000 {
+		t.Errorf("Frames = %d, want 48000", got.Frames)
+	}
+}
@@ -0,0 +1,293 @@
+package loudness
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	"github.com/cakturk/pkg/wav"
+)
+
+// Mode selects the measurement Normalize matches to its target.
+type Mode int
+
+const (
+	Integrated Mode = iota // integrated loudness, LUFS
+	Peak                   // sample peak, dBFS
+	TruePeak               // true peak, dBTP
+)
+
+// ErrClipping is returned by Normalize when the gain would push integer
+// samples past full scale and neither the limiter nor clipping is allowed.
+var ErrClipping = errors.New("loudness: normalized output would clip")
+
+// NormalizeOptions controls Normalize.
+type NormalizeOptions struct {
+	Mode   Mode
+	Target DB
+
+	// Limit enables a true peak limiter which keeps the output below
+	// Ceiling, in dBTP.
+	Limit   bool
+	Ceiling DB
+	// Lookahead and Release of the limiter; 5ms and 50ms if zero.
+	Lookahead time.Duration
+	Release   time.Duration
+
+	// AllowClipping lets integer samples clip instead of failing with
+	// ErrClipping. Clipped samples are counted in the result.
+	AllowClipping bool
+}
+
+// NormalizeResult describes what Normalize did.
+type NormalizeResult struct {
+	Input   *Result `json:"input"`
+	Gain    DB      `json:"gain"`    // gain applied before limiting
+	Limited int64   `json:"limited"` // frames attenuated by the limiter
+	Clipped int64   `json:"clipped"` // samples clipped while encoding
+}
+
+// Normalize measures src and writes a copy of it into w whose level matches
+// the target given in opts, which may be nil for the zero options. The output
+// keeps the sample format of src.
+func Normalize(w io.WriteSeeker, src *wav.WavFile, opts *NormalizeOptions) (*NormalizeResult, error) {
+	if opts == nil {
+		opts = &NormalizeOptions{}
+	}
+	in, err := Analyze(src)
+	if err != nil {
+		return nil, err
+	}
+	var level DB
+	switch opts.Mode {
+	case Integrated:
+		level = in.Integrated
+	case Peak:
+		level = in.Peak
+	case TruePeak:
+		level = in.TruePeak
+	default:
+		return nil, fmt.Errorf("loudness: unknown normalization mode: %d", opts.Mode)
+	}
+	if math.IsInf(float64(level), -1) {
+		return nil, errors.New("loudness: cannot normalize silence")
+	}
+	res := &NormalizeResult{Input: in, Gain: opts.Target - level}
+
+	isFloat := src.Fmt.Format() == wav.FormatIEEEFloat
+	if !isFloat && !opts.Limit && !opts.AllowClipping && in.Peak+res.Gain > 0 {
+		return res, fmt.Errorf("%w: peak %.2f dBFS", ErrClipping, in.Peak+res.Gain)
+	}
+
+	pcm := src.Data.PCMReader()
+	if pcm == nil {
+		return nil, errors.New("loudness: nil PCM reader")
+	}
+	if _, err := pcm.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	fr, err := src.FrameReader()
+	if err != nil {
+		return nil, err
+	}
+	dst, err := wav.CreateFmt(w, src.Fmt)
+	if err != nil {
+		return nil, err
+	}
+	fw, err := dst.FrameWriter()
+	if err != nil {
+		return nil, err
+	}
+
+	nchans := int(src.Fmt.NumChans)
+	gain := math.Pow(10, float64(res.Gain)/20)
+	var lim *limiter
+	if opts.Limit {
+		lim = newLimiter(nchans, int(src.Fmt.SampleRate), opts)
+	}
+	p := make([]float64, 4096*nchans)
+	for done := false; !done; {
+		n, err := fr.ReadFrames(p)
+		if err == io.EOF {
+			done = true
+		} else if err != nil {
+			return nil, err
+		}
+		out := p[:n*nchans]
+		for i := range out {
+			out[i] *= gain
+		}
+		if lim != nil {
+			out = lim.process(out)
+			if done {
+				out = append(out, lim.flush()...)
+			}
+		}
+		if _, err := fw.WriteFrames(out); err != nil {
+			return nil, err
+		}
+	}
+	if lim != nil {
+		res.Limited = lim.limited
+	}
+	res.Clipped = fw.Clipped()
+	if _, err := dst.Encode(w); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// limiter is a lookahead true peak limiter. Its gain reaches the required
+// attenuation before a peak arrives and recovers exponentially after it.
+type limiter struct {
+	nchans  int
+	ceiling float64
+	look    int // lookahead, frames
+	release float64
+
+	fir   [oversampling][truePeakPhase]float64
+	hist  [][]float64
+	delay []float64 // delayed audio, ring buffer of frames
+	pos   int
+	fill  int
+
+	req  minQueue  // required gains over the lookahead window
+	hold float64   // released gain
+	avg  []float64 // last look held gains
+	sum  float64
+	apos int
+
+	limited int64
+	out     []float64
+}
+
+func newLimiter(nchans, sampleRate int, opts *NormalizeOptions) *limiter {
+	look, release := opts.Lookahead, opts.Release
+	if look <= 0 {
+		look = 5 * time.Millisecond
+	}
+	if release <= 0 {
+		release = 50 * time.Millisecond
+	}
+	l := &limiter{
+		nchans:  nchans,
+		ceiling: math.Pow(10, float64(opts.Ceiling)/20),
+		look:    max(1, int(int64(look)*int64(sampleRate)/int64(time.Second))),
+		release: math.Exp(-1 / (release.Seconds() * float64(sampleRate))),
+		fir:     interpolator(),
+		hist:    make([][]float64, nchans),
+		hold:    1,
+	}
+	for c := range l.hist {
+		l.hist[c] = make([]float64, truePeakPhase)
+	}
+	// The interpolator reports the peaks around a frame truePeakPhase/2
+	// frames late and the averaged gain is only guaranteed to be below
+	// the gains required look frames ago.
+	l.delay = make([]float64, (l.look+truePeakPhase/2)*nchans)
+	l.avg = make([]float64, l.look)
+	for i := range l.avg {
+		l.avg[i] = 1
+	}
+	l.sum = float64(l.look)
+	return l
+}
+
+// process limits the frames in p and returns the frames which left the
+// delay line. The returned slice is only valid until the next call.
+func (l *limiter) process(p []float64) []float64 {
+	l.out = l.out[:0]
+	for i := 0; i+l.nchans <= len(p); i += l.nchans {
+		l.push(p[i : i+l.nchans])
+	}
+	return l.out
+}
+
+// flush drains the delay line and returns the frames which were left in it.
+func (l *limiter) flush() []float64 {
+	l.out = nil // keep the result of the last process call intact
+	zero := make([]float64, l.nchans)
+	for n := len(l.delay) / l.nchans; n > 0; n-- {
+		l.push(zero)
+	}
+	l.fill = 0
+	return l.out
+}
+
+func (l *limiter) push(frame []float64) {
+	// true peak of the frame truePeakPhase/2 frames ago
+	var peak float64
+	for c, s := range frame {
+		h := l.hist[c]
+		copy(h[1:], h[:len(h)-1])
+		h[0] = s
+		for _, phase := range l.fir {
+			var y float64
+			for j, g := range phase {
+				y += g * h[j]
+			}
+			peak = max(peak, math.Abs(y))
+		}
+		peak = max(peak, math.Abs(h[truePeakPhase/2]))
+	}
+	req := 1.0
+	if peak > l.ceiling {
+		req = l.ceiling / peak
+	}
+	// one extra step so that both intersample intervals next to a frame
+	// are covered
+	l.req.push(req, l.look+1)
+
+	g := l.req.min()
+	if g < l.hold {
+		l.hold = g
+	} else {
+		l.hold = g + (l.hold-g)*l.release
+	}
+	l.sum += l.hold - l.avg[l.apos]
+	l.avg[l.apos] = l.hold
+	l.apos = (l.apos + 1) % len(l.avg)
+	gain := min(1, l.sum/float64(len(l.avg)))
+
+	n := len(l.delay) / l.nchans
+	d := l.delay[l.pos*l.nchans : (l.pos+1)*l.nchans]
+	if l.fill == n {
+		if gain < 1 {
+			l.limited++
+		}
+		for _, s := range d {
+			l.out = append(l.out, s*gain)
+		}
+	} else {
+		l.fill++
+	}
+	copy(d, frame)
+	l.pos = (l.pos + 1) % n
+}
+
+// minQueue keeps the minimum of the last n values pushed.
+type minQueue struct {
+	vals []float64
+	idx  []int64
+	n    int64
+}
+
+func (q *minQueue) push(v float64, window int) {
+	for len(q.vals) > 0 && q.vals[len(q.vals)-1] >= v {
+		q.vals = q.vals[:len(q.vals)-1]
+		q.idx = q.idx[:len(q.idx)-1]
+	}
+	q.vals = append(q.vals, v)
+	q.idx = append(q.idx, q.n)
+	q.n++
+	if q.idx[0] <= q.n-1-int64(window) {
+		q.vals = q.vals[1:]
+		q.idx = q.idx[1:]
+	}
+}
+
+func (q *minQueue) min() float64 {
+	return q.vals[0]
+}
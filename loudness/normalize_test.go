@@ -0,0 +1,134 @@
+package loudness
+
+import (
+	"errors"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cakturk/pkg/wav"
+)
+
+func normalize(t *testing.T, src *wav.WavFile, opts *NormalizeOptions) (*NormalizeResult, *Result, error) {
+	t.Helper()
+	f, err := os.Create(filepath.Join(t.TempDir(), "out.wav"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	res, err := Normalize(f, src, opts)
+	if err != nil {
+		return res, nil, err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	wf, err := wav.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wf.Data.SubChunkSize != src.Data.SubChunkSize {
+		t.Errorf("data size = %d, want %d", wf.Data.SubChunkSize, src.Data.SubChunkSize)
+	}
+	out, err := Analyze(wf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res, out, nil
+}
+
+func TestNormalizeIntegrated(t *testing.T) {
+	src := sineWav(t, 1000, 0, segment{-30, 5})
+	res, out, err := normalize(t, src, &NormalizeOptions{Mode: Integrated, Target: -23})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !near(res.Gain, 7, 0.1) {
+		t.Errorf("Gain = %.2f, want 7", res.Gain)
+	}
+	if !near(out.Integrated, -23, 0.05) {
+		t.Errorf("Integrated = %.2f, want -23", out.Integrated)
+	}
+}
+
+func TestNormalizePeak(t *testing.T) {
+	src := sineWav(t, 1000, 0, segment{-12, 1})
+	_, out, err := normalize(t, src, &NormalizeOptions{Mode: Peak, Target: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !near(out.Peak, -1, 0.01) {
+		t.Errorf("Peak = %.2f, want -1", out.Peak)
+	}
+}
+
+func TestNormalizeClipping(t *testing.T) {
+	src := sineWav(t, 1000, 0, segment{-6, 1})
+	opts := &NormalizeOptions{Mode: Integrated, Target: 3}
+	if _, _, err := normalize(t, src, opts); !errors.Is(err, ErrClipping) {
+		t.Fatalf("got %v, want ErrClipping", err)
+	}
+
+	opts.AllowClipping = true
+	res, _, err := normalize(t, src, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Clipped == 0 {
+		t.Error("expected clipped samples to be reported")
+	}
+}
+
+func TestNormalizeDefaults(t *testing.T) {
+	// nil options are the zero options: integrated loudness of 0 LUFS
+	src := sineWav(t, 1000, 0, segment{-30, 1})
+	_, out, err := normalize(t, src, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !near(out.Integrated, 0, 0.05) {
+		t.Errorf("Integrated = %.2f, want 0", out.Integrated)
+	}
+
+	src.Data = wav.DataChunk{SubChunkID: src.Data.SubChunkID, SubChunkSize: src.Data.SubChunkSize}
+	if _, _, err := normalize(t, src, nil); err == nil {
+		t.Error("Normalize() without a PCM reader succeeded")
+	}
+}
+
+func TestNormalizeLimiter(t *testing.T) {
+	src := sineWav(t, 12000, math.Pi/4, segment{-20, 0.5}, segment{-12, 0.5})
+	opts := &NormalizeOptions{Mode: Peak, Target: 0, Limit: true, Ceiling: -1}
+	res, out, err := normalize(t, src, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.TruePeak > -1+0.05 {
+		t.Errorf("TruePeak = %.2f, want at most -1", out.TruePeak)
+	}
+	if res.Clipped != 0 || res.Limited == 0 {
+		t.Errorf("Clipped = %d, Limited = %d", res.Clipped, res.Limited)
+	}
+	// the quiet part is 8 dB below the peak and left alone
+	if !near(out.Channels[0].Peak, -1-3.01, 0.1) {
+		t.Errorf("Peak = %.2f", out.Channels[0].Peak)
+	}
+}
+
+func TestLimiterShortInput(t *testing.T) {
+	l := newLimiter(2, 48000, &NormalizeOptions{Ceiling: -1})
+	in := []float64{0.1, 0.2, 0.3, 0.4, 0.5, 0.6}
+	out := append([]float64(nil), l.process(in)...)
+	out = append(out, l.flush()...)
+	if len(out) != len(in) {
+		t.Fatalf("got %d samples, want %d", len(out), len(in))
+	}
+	for i := range in {
+		if out[i] != in[i] {
+			t.Errorf("got %v, want %v", out, in)
+			break
+		}
+	}
+}
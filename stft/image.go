@@ -0,0 +1,98 @@
+package stft
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+)
+
+// Colormap maps a level in [0, 1] to a color.
+type Colormap func(v float64) color.Color
+
+// Grayscale maps levels from black to white.
+func Grayscale(v float64) color.Color {
+	return color.Gray{Y: uint8(v*255 + 0.5)}
+}
+
+var heatStops = []color.RGBA{
+	{0x00, 0x00, 0x00, 0xff},
+	{0x20, 0x0c, 0x70, 0xff},
+	{0x90, 0x20, 0x80, 0xff},
+	{0xe0, 0x40, 0x30, 0xff},
+	{0xfc, 0xb0, 0x20, 0xff},
+	{0xfc, 0xff, 0xa4, 0xff},
+}
+
+// Heat maps levels from black through purple, red and yellow to white.
+func Heat(v float64) color.Color {
+	pos := v * float64(len(heatStops)-1)
+	i := min(int(pos), len(heatStops)-2)
+	f := pos - float64(i)
+	a, b := heatStops[i], heatStops[i+1]
+	lerp := func(x, y uint8) uint8 {
+		return uint8(float64(x) + (float64(y)-float64(x))*f + 0.5)
+	}
+	return color.RGBA{lerp(a.R, b.R), lerp(a.G, b.G), lerp(a.B, b.B), 0xff}
+}
+
+// ImageOptions controls how a spectrogram is rendered.
+type ImageOptions struct {
+	// Colormap defaults to Grayscale.
+	Colormap Colormap
+	// Min and Max are the levels, in dB, mapped to the ends of the
+	// colormap. They default to -120 and 0 dB. If they are equal, levels
+	// below them map to the start of the colormap and the others to its
+	// end.
+	Min, Max float64
+}
+
+// Image renders s with time running left to right and frequency bottom to
+// top, one pixel per frame and bin.
+func (s *Spectrogram) Image(opts *ImageOptions) image.Image {
+	var o ImageOptions
+	if opts != nil {
+		o = *opts
+	}
+	if o.Colormap == nil {
+		o.Colormap = Grayscale
+	}
+	if o.Min == 0 && o.Max == 0 {
+		o.Min, o.Max = -120, 0
+	}
+	bins := s.Size/2 + 1
+	rect := image.Rect(0, 0, len(s.Frames), bins)
+	var img interface {
+		image.Image
+		Set(x, y int, c color.Color)
+	}
+	if isGray(o.Colormap) {
+		img = image.NewGray(rect)
+	} else {
+		img = image.NewRGBA(rect)
+	}
+	span := o.Max - o.Min
+	for x, frame := range s.Frames {
+		for k, db := range frame {
+			var v float64
+			switch {
+			case span != 0:
+				v = max(0, min(1, (db-o.Min)/span))
+			case db >= o.Max:
+				v = 1
+			}
+			img.Set(x, bins-1-k, o.Colormap(v))
+		}
+	}
+	return img
+}
+
+func isGray(cm Colormap) bool {
+	_, ok := cm(0.5).(color.Gray)
+	return ok
+}
+
+// WritePNG renders s and encodes it as a PNG image into w.
+func (s *Spectrogram) WritePNG(w io.Writer, opts *ImageOptions) error {
+	return png.Encode(w, s.Image(opts))
+}
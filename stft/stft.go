@@ -0,0 +1,221 @@
+// Package stft computes short-time Fourier transforms of wav files and
+// renders them as spectrogram images.
+package stft
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/cmplx"
+
+	"github.com/cakturk/pkg/wav"
+)
+
+// Window is an analysis window function.
+type Window int
+
+const (
+	Hann Window = iota
+	Hamming
+	Blackman
+	Rectangular
+)
+
+func (w Window) coefficients(n int) []float64 {
+	c := make([]float64, n)
+	for i := range c {
+		x := 2 * math.Pi * float64(i) / float64(n)
+		switch w {
+		case Hann:
+			c[i] = 0.5 - 0.5*math.Cos(x)
+		case Hamming:
+			c[i] = 0.54 - 0.46*math.Cos(x)
+		case Blackman:
+			c[i] = 0.42 - 0.5*math.Cos(x) + 0.08*math.Cos(2*x)
+		case Rectangular:
+			c[i] = 1
+		}
+	}
+	return c
+}
+
+// Options controls the analysis. The zero value analyzes the first channel
+// with 1024 point Hann windows overlapping by 75%.
+type Options struct {
+	Size   int // FFT size, a power of two
+	Hop    int // frames between the start of two windows
+	Window Window
+	// Channel is the channel to analyze. A negative value mixes all
+	// channels.
+	Channel int
+}
+
+// Floor is the level reported for bins without any energy.
+const Floor = -200
+
+// Spectrogram holds magnitude frames in dB, one for each window. Each frame
+// has Size/2+1 bins from 0 Hz up to the Nyquist frequency. A full scale sine
+// peaks at about 0 dB.
+type Spectrogram struct {
+	SampleRate int
+	Size       int
+	Hop        int
+	Frames     [][]float64
+}
+
+// BinFrequency returns the center frequency of bin k in Hz.
+func (s *Spectrogram) BinFrequency(k int) float64 {
+	return float64(k) * float64(s.SampleRate) / float64(s.Size)
+}
+
+// FrameTime returns the start of frame i in seconds.
+func (s *Spectrogram) FrameTime(i int) float64 {
+	return float64(i*s.Hop) / float64(s.SampleRate)
+}
+
+// Analyze reads wf and returns its spectrogram. The last window is padded
+// with zeros.
+func Analyze(wf *wav.WavFile, opts *Options) (*Spectrogram, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+	size, hop := opts.Size, opts.Hop
+	if size == 0 {
+		size = 1024
+	}
+	if hop == 0 {
+		hop = size / 4
+	}
+	if size < 2 || size&(size-1) != 0 {
+		return nil, fmt.Errorf("stft: size %d is not a power of two", size)
+	}
+	if hop < 1 || hop > size {
+		return nil, fmt.Errorf("stft: invalid hop: %d", hop)
+	}
+	if opts.Window < Hann || opts.Window > Rectangular {
+		return nil, fmt.Errorf("stft: unknown window: %d", opts.Window)
+	}
+	nchans := int(wf.Fmt.NumChans)
+	if opts.Channel >= nchans {
+		return nil, fmt.Errorf("stft: no channel %d in a %d channel file", opts.Channel, nchans)
+	}
+	pcm := wf.Data.PCMReader()
+	if pcm == nil {
+		return nil, errors.New("stft: nil PCM reader")
+	}
+	if _, err := pcm.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	fr, err := wf.FrameReader()
+	if err != nil {
+		return nil, err
+	}
+
+	t := newTransform(size, opts.Window)
+	s := &Spectrogram{SampleRate: int(wf.Fmt.SampleRate), Size: size, Hop: hop}
+	var (
+		buf     []float64 // mono samples, buf[0] starts the next window
+		covered int       // samples of buf included in the last window
+	)
+	p := make([]float64, 4096*nchans)
+	for {
+		n, err := fr.ReadFrames(p)
+		for i := 0; i < n; i++ {
+			frame := p[i*nchans : (i+1)*nchans]
+			if opts.Channel >= 0 {
+				buf = append(buf, frame[opts.Channel])
+				continue
+			}
+			var sum float64
+			for _, v := range frame {
+				sum += v
+			}
+			buf = append(buf, sum/float64(nchans))
+		}
+		for len(buf) >= size {
+			s.Frames = append(s.Frames, t.magnitudes(buf[:size]))
+			buf = buf[:copy(buf, buf[hop:])]
+			covered = size - hop
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(buf) > covered || len(s.Frames) == 0 && len(buf) > 0 {
+		s.Frames = append(s.Frames, t.magnitudes(buf))
+	}
+	return s, nil
+}
+
+type transform struct {
+	window []float64
+	norm   float64
+	x      []complex128
+}
+
+func newTransform(size int, w Window) *transform {
+	t := &transform{
+		window: w.coefficients(size),
+		x:      make([]complex128, size),
+	}
+	for _, c := range t.window {
+		t.norm += c
+	}
+	// a sine's energy is split between the positive and negative bins
+	t.norm /= 2
+	return t
+}
+
+// magnitudes returns the spectrum of the windowed samples in dB. Missing
+// samples are zero.
+func (t *transform) magnitudes(samples []float64) []float64 {
+	for i := range t.x {
+		var v float64
+		if i < len(samples) {
+			v = samples[i] * t.window[i]
+		}
+		t.x[i] = complex(v, 0)
+	}
+	fft(t.x)
+	out := make([]float64, len(t.x)/2+1)
+	for k := range out {
+		m := cmplx.Abs(t.x[k]) / t.norm
+		if m == 0 {
+			out[k] = Floor
+			continue
+		}
+		out[k] = max(Floor, 20*math.Log10(m))
+	}
+	return out
+}
+
+// fft computes the discrete Fourier transform of x in place. len(x) must be
+// a power of two.
+func fft(x []complex128) {
+	n := len(x)
+	for i, j := 1, 0; i < n; i++ {
+		bit := n >> 1
+		for ; j&bit != 0; bit >>= 1 {
+			j ^= bit
+		}
+		j ^= bit
+		if i < j {
+			x[i], x[j] = x[j], x[i]
+		}
+	}
+	for size := 2; size <= n; size <<= 1 {
+		w := cmplx.Exp(complex(0, -2*math.Pi/float64(size)))
+		for start := 0; start < n; start += size {
+			wk := complex(1, 0)
+			for k := 0; k < size/2; k++ {
+				a, b := x[start+k], x[start+k+size/2]*wk
+				x[start+k], x[start+k+size/2] = a+b, a-b
+				wk *= w
+			}
+		}
+	}
+}
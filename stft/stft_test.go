@@ -0,0 +1,160 @@
+package stft
+
+import (
+	"bytes"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+	"math/cmplx"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cakturk/pkg/wav"
+	"github.com/cakturk/pkg/wavgen"
+)
+
+// sineWav writes an 8 kHz 16-bit wav file with a sine wave of the given
+// frequency on each channel.
+func sineWav(t *testing.T, freqs []float64, frames int) *wav.WavFile {
+	t.Helper()
+	f, err := os.Create(filepath.Join(t.TempDir(), "in.wav"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+	sigs := make([]wavgen.Signal, len(freqs))
+	for c, freq := range freqs {
+		sigs[c] = wavgen.Sine(freq, 0.5)
+	}
+	format := wavgen.Format{SampleRate: 8000, Channels: len(freqs), Bits: 16}
+	if err := wavgen.Write(f, format, int64(frames), sigs...); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	wf, err := wav.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return wf
+}
+
+func TestFFT(t *testing.T) {
+	x := []complex128{1, 2, 3, 4, 0, -1, 2, 5}
+	want := make([]complex128, len(x))
+	for k := range want {
+		for n, v := range x {
+			want[k] += v * cmplx.Exp(complex(0, -2*math.Pi*float64(k*n)/float64(len(x))))
+		}
+	}
+	fft(x)
+	for k := range x {
+		if cmplx.Abs(x[k]-want[k]) > 1e-9 {
+			t.Errorf("bin %d: got %v, want %v", k, x[k], want[k])
+		}
+	}
+}
+
+func peakBin(frame []float64) int {
+	best := 0
+	for k, v := range frame {
+		if v > frame[best] {
+			best = k
+		}
+	}
+	return best
+}
+
+func TestAnalyze(t *testing.T) {
+	// 1kHz in the left and 2kHz in the right channel, bins are 31.25Hz
+	wf := sineWav(t, []float64{1000, 2000}, 1000)
+	tests := []struct {
+		channel int
+		bins    []int
+	}{
+		{0, []int{32}},
+		{1, []int{64}},
+		{-1, []int{32, 64}},
+	}
+	for _, tt := range tests {
+		s, err := Analyze(wf, &Options{Size: 256, Hop: 128, Channel: tt.channel})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// 1000 frames: 6 full windows and a padded one
+		if len(s.Frames) != 7 || len(s.Frames[0]) != 129 {
+			t.Fatalf("got %d frames of %d bins", len(s.Frames), len(s.Frames[0]))
+		}
+		frame := s.Frames[2]
+		if got := peakBin(frame); tt.channel >= 0 && got != tt.bins[0] {
+			t.Errorf("channel %d: peak at bin %d, want %d", tt.channel, got, tt.bins[0])
+		}
+		want := -6.02 // 0.5 amplitude
+		if tt.channel < 0 {
+			want -= 6.02 // mixed with the other channel
+		}
+		for _, b := range tt.bins {
+			if math.Abs(frame[b]-want) > 0.1 {
+				t.Errorf("channel %d: bin %d is %.2f dB, want %.2f", tt.channel, b, frame[b], want)
+			}
+		}
+		if s.BinFrequency(tt.bins[0]) != 1000*float64(tt.bins[0])/32 {
+			t.Errorf("BinFrequency(%d) = %v", tt.bins[0], s.BinFrequency(tt.bins[0]))
+		}
+	}
+	if _, err := Analyze(wf, &Options{Size: 100}); err == nil {
+		t.Error("expected an error for a size which is not a power of two")
+	}
+	if _, err := Analyze(wf, &Options{Window: Rectangular + 1}); err == nil {
+		t.Error("expected an error for an unknown window")
+	}
+	wf.Data = wav.DataChunk{SubChunkID: wf.Data.SubChunkID, SubChunkSize: wf.Data.SubChunkSize}
+	if _, err := Analyze(wf, nil); err == nil {
+		t.Error("expected an error without a PCM reader")
+	}
+}
+
+func TestWritePNG(t *testing.T) {
+	s, err := Analyze(sineWav(t, []float64{1000}, 2048), &Options{Size: 64, Hop: 64})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, cm := range []Colormap{Grayscale, Heat} {
+		var buf bytes.Buffer
+		if err := s.WritePNG(&buf, &ImageOptions{Colormap: cm}); err != nil {
+			t.Fatal(err)
+		}
+		img, err := png.Decode(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b := img.Bounds(); b.Dx() != 32 || b.Dy() != 33 {
+			t.Fatalf("bounds = %v", b)
+		}
+		// 1kHz is bin 8 from the bottom and the brightest row
+		r, _, _, _ := img.At(5, 32-8).RGBA()
+		r0, _, _, _ := img.At(5, 32-16).RGBA()
+		if r <= r0 {
+			t.Errorf("tone row is not brighter: %d <= %d", r, r0)
+		}
+	}
+	// equal levels threshold at them instead of dividing by zero
+	img := s.Image(&ImageOptions{Min: -20, Max: -20})
+	for x := 0; x < 32; x++ {
+		for y := 0; y < 33; y++ {
+			want := color.Gray{}
+			if s.Frames[x][32-y] >= -20 {
+				want.Y = 0xff
+			}
+			if got := img.At(x, y); got != want {
+				t.Fatalf("pixel (%d, %d) = %v, want %v", x, y, got, want)
+			}
+		}
+	}
+	if got := Heat(1).(color.RGBA); got != heatStops[len(heatStops)-1] {
+		t.Errorf("Heat(1) = %v", got)
+	}
+}
@@ -0,0 +1,110 @@
+package wav
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// CueChunk holds the cue points (markers) of a wav file.
+type CueChunk struct {
+	SubChunkID   [4]byte // "cue "
+	SubChunkSize uint32
+
+	Points []CuePoint
+}
+
+// CuePoint marks a position in the data chunk. SampleOffset is the frame
+// index the marker points at.
+type CuePoint struct {
+	ID           uint32
+	Position     uint32
+	DataChunkID  [4]byte // data
+	ChunkStart   uint32
+	BlockStart   uint32
+	SampleOffset uint32
+}
+
+const cuePointSize = 24
+
+// NewCueChunk returns a cue chunk with a point at each of the given frame
+// offsets. Points are numbered starting from 1.
+func NewCueChunk(offsets ...uint32) *CueChunk {
+	c := &CueChunk{SubChunkID: CUE}
+	for _, off := range offsets {
+		c.Add(off)
+	}
+	return c
+}
+
+// Add appends a new cue point at the given frame offset and returns its ID.
+func (c *CueChunk) Add(off uint32) uint32 {
+	id := uint32(len(c.Points) + 1)
+	c.Points = append(c.Points, CuePoint{
+		ID:           id,
+		Position:     off,
+		DataChunkID:  DATA,
+		SampleOffset: off,
+	})
+	return id
+}
+
+func (c *CueChunk) size() int64 {
+	if c == nil {
+		return 0
+	}
+	return int64(4+len(c.Points)*cuePointSize) + 8
+}
+
+func (c *CueChunk) Unpack(r io.Reader) error {
+	er := &errReader{r: r}
+	p := make([]byte, cuePointSize)
+
+	er.ReadFull(c.SubChunkID[:])
+	if c.SubChunkID != CUE {
+		return errors.New("wav: malformed cue chunk header")
+	}
+	er.ReadFull(p[:4])
+	c.SubChunkSize = binary.LittleEndian.Uint32(p)
+	er.ReadFull(p[:4])
+	n := binary.LittleEndian.Uint32(p)
+	if 4+uint64(n)*cuePointSize > uint64(c.SubChunkSize) {
+		return errors.New("wav: malformed cue chunk size")
+	}
+	c.Points = make([]CuePoint, 0, n)
+	for i := uint32(0); i < n && er.err == nil; i++ {
+		er.ReadFull(p)
+		var cp CuePoint
+		cp.ID = binary.LittleEndian.Uint32(p[0:])
+		cp.Position = binary.LittleEndian.Uint32(p[4:])
+		copy(cp.DataChunkID[:], p[8:12])
+		cp.ChunkStart = binary.LittleEndian.Uint32(p[12:])
+		cp.BlockStart = binary.LittleEndian.Uint32(p[16:])
+		cp.SampleOffset = binary.LittleEndian.Uint32(p[20:])
+		c.Points = append(c.Points, cp)
+	}
+	return er.err
+}
+
+func (c *CueChunk) Pack(w io.Writer) error {
+	ew := &errWriter{w: w}
+	p := make([]byte, cuePointSize)
+
+	c.SubChunkID = CUE
+	c.SubChunkSize = uint32(c.size() - 8)
+	ew.write(c.SubChunkID[:])
+	binary.LittleEndian.PutUint32(p, c.SubChunkSize)
+	ew.write(p[:4])
+	binary.LittleEndian.PutUint32(p, uint32(len(c.Points)))
+	ew.write(p[:4])
+	for _, cp := range c.Points {
+		binary.LittleEndian.PutUint32(p[0:], cp.ID)
+		binary.LittleEndian.PutUint32(p[4:], cp.Position)
+		copy(p[8:12], cp.DataChunkID[:])
+		binary.LittleEndian.PutUint32(p[12:], cp.ChunkStart)
+		binary.LittleEndian.PutUint32(p[16:], cp.BlockStart)
+		binary.LittleEndian.PutUint32(p[20:], cp.SampleOffset)
+		ew.write(p)
+	}
+	return ew.err
+}
@@ -0,0 +1,43 @@
+package wav
+
+import (
+	"io"
+	"testing"
+)
+
+func TestCueChunk(t *testing.T) {
+	in := writeFrames(t, floatFmt(8000, 1, 32), make([]float64, 16))
+	w := tempFile(t)
+	wf, err := CreateFmt(w, in.Fmt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wf.Data.PCMWriter().Write(make([]byte, 64)); err != nil {
+		t.Fatal(err)
+	}
+	wf.Cue = NewCueChunk(3, 10)
+	wf.List = &ListChunk{
+		SubChunkID: LIST,
+		TypeID:     INFO,
+		SubChunks:  []InfoChunk{{ID: INAM, Text: []byte("cue")}},
+	}
+	if _, err := wf.Encode(w); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Decode(w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Cue == nil || len(got.Cue.Points) != 2 {
+		t.Fatalf("unexpected cue chunk: %+v", got.Cue)
+	}
+	if p := got.Cue.Points[1]; p.ID != 2 || p.SampleOffset != 10 || p.DataChunkID != DATA {
+		t.Errorf("unexpected cue point: %+v", p)
+	}
+	if got.List == nil || got.List.InfoChunk(INAM) != "cue" {
+		t.Errorf("unexpected list chunk: %+v", got.List)
+	}
+}
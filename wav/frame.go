@@ -0,0 +1,229 @@
+package wav
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// FrameReader decodes interleaved PCM frames into float64 samples
+// normalized to [-1, 1].
+type FrameReader struct {
+	r     io.Reader
+	f     FmtChunk
+	width int // bytes per sample
+	buf   []byte
+}
+
+// NewFrameReader returns a FrameReader that decodes PCM samples read from r
+// according to the fmt chunk f.
+func NewFrameReader(r io.Reader, f *FmtChunk) (*FrameReader, error) {
+	width, err := sampleWidth(f)
+	if err != nil {
+		return nil, err
+	}
+	return &FrameReader{r: r, f: *f, width: width}, nil
+}
+
+// FrameReader returns a FrameReader over the PCM samples of the file.
+func (wf *WavFile) FrameReader() (*FrameReader, error) {
+	r := wf.Data.PCMReader()
+	if r == nil {
+		return nil, errors.New("wav: nil PCM reader")
+	}
+	return NewFrameReader(r, &wf.Fmt)
+}
+
+// RangeFrameReader returns a FrameReader over the frames in [start, end)
+// that is independent of the PCM reader and of other readers. See
+// DataChunk.NewReader for when it is safe for concurrent use.
+func (wf *WavFile) RangeFrameReader(start, end int64) (*FrameReader, error) {
+	if start < 0 || start > end || end > wf.NumFrames() {
+		return nil, fmt.Errorf("wav: invalid frame range [%d, %d)", start, end)
+	}
+	if wf.Data.ra == nil {
+		return nil, errors.New("wav: source is not an io.ReaderAt")
+	}
+	blockAlign := int64(wf.Fmt.BlockAlign)
+	r := io.NewSectionReader(wf.Data.ra, wf.Data.off+start*blockAlign, (end-start)*blockAlign)
+	return NewFrameReader(r, &wf.Fmt)
+}
+
+// ReadFrames reads up to len(p)/NumChans frames into p and returns the
+// number of frames read. Samples are interleaved. At the end of the stream it
+// returns 0, io.EOF. A trailing partial frame is reported as
+// io.ErrUnexpectedEOF.
+func (fr *FrameReader) ReadFrames(p []float64) (int, error) {
+	nchans := int(fr.f.NumChans)
+	frames := len(p) / nchans
+	if frames == 0 {
+		return 0, nil
+	}
+	blockAlign := int(fr.f.BlockAlign)
+	if need := frames * blockAlign; cap(fr.buf) < need {
+		fr.buf = make([]byte, need)
+	}
+	buf := fr.buf[:frames*blockAlign]
+	n, err := io.ReadFull(fr.r, buf)
+	if err == io.ErrUnexpectedEOF && n%blockAlign == 0 {
+		err = nil
+	}
+	frames = n / blockAlign
+	decodeSamples(p[:frames*nchans], buf[:frames*blockAlign], &fr.f, fr.width)
+	return frames, err
+}
+
+// FrameSource is implemented by readers of interleaved frames, such as
+// FrameReader.
+type FrameSource interface {
+	ReadFrames(p []float64) (int, error)
+}
+
+// ReadFullFrames reads len(p)/nchans frames from r into p, unless the stream
+// ends or fails first. It returns the number of frames read and, if fewer,
+// the error which stopped it: io.EOF at the end of the stream.
+func ReadFullFrames(r FrameSource, p []float64, nchans int) (int, error) {
+	var n int
+	for n < len(p)/nchans {
+		m, err := r.ReadFrames(p[n*nchans:])
+		n += m
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// FrameWriter encodes float64 samples into interleaved PCM frames.
+// Samples out of the [-1, 1] range are clipped and counted.
+type FrameWriter struct {
+	w       io.Writer
+	f       FmtChunk
+	width   int
+	buf     []byte
+	clipped int64
+}
+
+// NewFrameWriter returns a FrameWriter that encodes samples into w according
+// to the fmt chunk f.
+func NewFrameWriter(w io.Writer, f *FmtChunk) (*FrameWriter, error) {
+	width, err := sampleWidth(f)
+	if err != nil {
+		return nil, err
+	}
+	return &FrameWriter{w: w, f: *f, width: width}, nil
+}
+
+// FrameWriter returns a FrameWriter over the PCM writer of a file made with
+// Create or CreateFmt.
+func (wf *WavFile) FrameWriter() (*FrameWriter, error) {
+	w := wf.Data.PCMWriter()
+	if w == nil {
+		return nil, errors.New("wav: nil PCM writer")
+	}
+	return NewFrameWriter(w, &wf.Fmt)
+}
+
+// WriteFrames encodes the interleaved samples in p and returns the number of
+// frames written. len(p) must be a multiple of NumChans.
+func (fw *FrameWriter) WriteFrames(p []float64) (int, error) {
+	nchans := int(fw.f.NumChans)
+	if len(p)%nchans != 0 {
+		return 0, fmt.Errorf("wav: %d samples is not a multiple of %d channels", len(p), nchans)
+	}
+	need := len(p) * fw.width
+	if cap(fw.buf) < need {
+		fw.buf = make([]byte, need)
+	}
+	buf := fw.buf[:need]
+	fw.clipped += int64(encodeSamples(buf, p, &fw.f, fw.width))
+	n, err := fw.w.Write(buf)
+	return n / int(fw.f.BlockAlign), err
+}
+
+// Clipped returns the number of samples clipped so far.
+func (fw *FrameWriter) Clipped() int64 {
+	return fw.clipped
+}
+
+func sampleWidth(f *FmtChunk) (int, error) {
+	if f.NumChans == 0 || f.BlockAlign == 0 || f.BlockAlign%f.NumChans != 0 {
+		return 0, fmt.Errorf("wav: invalid block align %d for %d channels", f.BlockAlign, f.NumChans)
+	}
+	width := int(f.BlockAlign / f.NumChans)
+	switch f.Format() {
+	case FormatPCM:
+		if width < 1 || width > 4 {
+			return 0, fmt.Errorf("wav: unsupported sample width: %d", width)
+		}
+	case FormatIEEEFloat:
+		if width != 4 && width != 8 {
+			return 0, fmt.Errorf("wav: unsupported float sample width: %d", width)
+		}
+	default:
+		return 0, fmt.Errorf("wav: unsupported audio format: %#04x", f.Format())
+	}
+	return width, nil
+}
+
+func decodeSamples(dst []float64, src []byte, f *FmtChunk, width int) {
+	float := f.Format() == FormatIEEEFloat
+	for i := range dst {
+		b := src[i*width : (i+1)*width]
+		switch {
+		case float && width == 4:
+			dst[i] = float64(math.Float32frombits(binary.LittleEndian.Uint32(b)))
+		case float:
+			dst[i] = math.Float64frombits(binary.LittleEndian.Uint64(b))
+		case width == 1:
+			// 8-bit PCM is unsigned
+			dst[i] = float64(int(b[0])-0x80) / 0x80
+		default:
+			var v int64
+			for j := width - 1; j >= 0; j-- {
+				v = v<<8 | int64(b[j])
+			}
+			// sign extend
+			shift := 64 - 8*uint(width)
+			v = v << shift >> shift
+			dst[i] = float64(v) / float64(int64(1)<<(8*width-1))
+		}
+	}
+}
+
+func encodeSamples(dst []byte, src []float64, f *FmtChunk, width int) (clipped int) {
+	float := f.Format() == FormatIEEEFloat
+	for i, s := range src {
+		b := dst[i*width : (i+1)*width]
+		if float {
+			if width == 4 {
+				binary.LittleEndian.PutUint32(b, math.Float32bits(float32(s)))
+			} else {
+				binary.LittleEndian.PutUint64(b, math.Float64bits(s))
+			}
+			continue
+		}
+		scale := float64(int64(1) << (8*width - 1))
+		v := math.Round(s * scale)
+		if v > scale-1 {
+			if v > scale {
+				clipped++
+			}
+			v = scale - 1
+		} else if v < -scale {
+			clipped++
+			v = -scale
+		}
+		iv := int64(v)
+		if width == 1 {
+			b[0] = byte(iv + 0x80)
+			continue
+		}
+		for j := 0; j < width; j++ {
+			b[j] = byte(iv >> (8 * j))
+		}
+	}
+	return clipped
+}
@@ -0,0 +1,172 @@
+package wav
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func tempFile(t testing.TB) *os.File {
+	t.Helper()
+	f, err := os.Create(filepath.Join(t.TempDir(), "test.wav"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+	return f
+}
+
+func floatFmt(sampleRate, nchans, nbits int) FmtChunk {
+	blockAlign := uint16(nchans * nbits / 8)
+	return FmtChunk{
+		SubChunkID:    FMT,
+		SubChunkSize:  0x10,
+		AudioFormat:   FormatIEEEFloat,
+		NumChans:      uint16(nchans),
+		SampleRate:    uint32(sampleRate),
+		ByteRate:      uint32(sampleRate) * uint32(blockAlign),
+		BlockAlign:    blockAlign,
+		BitsPerSample: uint16(nbits),
+	}
+}
+
+func TestFrameRoundTrip(t *testing.T) {
+	samples := []float64{0, 0.5, -0.5, 0.25, -1, 0.75}
+	tests := []struct {
+		format uint16
+		nbits  int
+		nchans int
+	}{
+		{FormatPCM, 8, 1},
+		{FormatPCM, 16, 2},
+		{FormatPCM, 24, 3},
+		{FormatPCM, 32, 6},
+		{FormatIEEEFloat, 32, 2},
+		{FormatIEEEFloat, 64, 1},
+	}
+	for _, tt := range tests {
+		f := floatFmt(8000, tt.nchans, tt.nbits)
+		f.AudioFormat = tt.format
+		var buf bytes.Buffer
+		fw, err := NewFrameWriter(&buf, &f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n, err := fw.WriteFrames(samples); err != nil || n != len(samples)/tt.nchans {
+			t.Fatalf("%d-bit: WriteFrames() = %d, %v", tt.nbits, n, err)
+		}
+		fr, err := NewFrameReader(&buf, &f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := make([]float64, len(samples)+tt.nchans)
+		n, err := fr.ReadFrames(got)
+		if err != nil || n != len(samples)/tt.nchans {
+			t.Fatalf("%d-bit: ReadFrames() = %d, %v", tt.nbits, n, err)
+		}
+		for i, want := range samples {
+			if got[i] != want {
+				t.Errorf("%d-bit: sample %d: got %v, want %v", tt.nbits, i, got[i], want)
+			}
+		}
+		if _, err := fr.ReadFrames(got); err != io.EOF {
+			t.Errorf("%d-bit: got %v, want EOF", tt.nbits, err)
+		}
+	}
+}
+
+func TestFrameWriterClipped(t *testing.T) {
+	f := floatFmt(8000, 1, 16)
+	f.AudioFormat = FormatPCM
+	var buf bytes.Buffer
+	fw, err := NewFrameWriter(&buf, &f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fw.WriteFrames([]float64{1, 1.5, -1, -2, 0}); err != nil {
+		t.Fatal(err)
+	}
+	if got := fw.Clipped(); got != 2 {
+		t.Errorf("Clipped() = %d, want 2", got)
+	}
+	want := []byte{0xff, 0x7f, 0xff, 0x7f, 0x00, 0x80, 0x00, 0x80, 0x00, 0x00}
+	if got := buf.Bytes(); !bytes.Equal(got, want) {
+		t.Errorf("got: %#02v, want: %#02v", got, want)
+	}
+}
+
+// oneFrame reads a single frame per call.
+type oneFrame struct {
+	fr     *FrameReader
+	nchans int
+}
+
+func (o oneFrame) ReadFrames(p []float64) (int, error) {
+	return o.fr.ReadFrames(p[:min(len(p), o.nchans)])
+}
+
+func TestReadFullFrames(t *testing.T) {
+	f := floatFmt(8000, 2, 16)
+	f.AudioFormat = FormatPCM
+	var buf bytes.Buffer
+	fw, err := NewFrameWriter(&buf, &f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fw.WriteFrames([]float64{0, 0.5, -0.5, 0.25, 0.75, -1}); err != nil {
+		t.Fatal(err)
+	}
+	fr, err := NewFrameReader(&buf, &f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := make([]float64, 4)
+	if n, err := ReadFullFrames(oneFrame{fr, 2}, p, 2); n != 2 || err != nil {
+		t.Fatalf("ReadFullFrames() = %d, %v, want 2 frames", n, err)
+	}
+	if p[3] != 0.25 {
+		t.Errorf("got %v", p)
+	}
+	if n, err := ReadFullFrames(oneFrame{fr, 2}, p, 2); n != 1 || err != io.EOF {
+		t.Errorf("ReadFullFrames() = %d, %v, want 1 frame and EOF", n, err)
+	}
+}
+
+func TestDecodeExtensible(t *testing.T) {
+	f := tempFile(t)
+	ext := floatFmt(48000, 2, 24)
+	ext.AudioFormat = FormatExtensible
+	ext.SubChunkSize = 40
+	ext.CbSize = 22
+	ext.ValidBitsPerSample = 24
+	ext.ChannelMask = 0x3
+	ext.SubFormat = SubFormatPCM
+	wf, err := CreateFmt(f, ext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wf.Data.PCMWriter().Write(make([]byte, 60)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wf.Encode(f); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Fmt != ext {
+		t.Errorf("got: %+v, want: %+v", got.Fmt, ext)
+	}
+	if got.Fmt.Format() != FormatPCM {
+		t.Errorf("Format() = %#x, want %#x", got.Fmt.Format(), FormatPCM)
+	}
+	if got.Data.SubChunkSize != 60 {
+		t.Errorf("data size: got %d, want 60", got.Data.SubChunkSize)
+	}
+}
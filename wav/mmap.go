@@ -0,0 +1,132 @@
+package wav
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"unsafe"
+)
+
+var errClosed = errors.New("wav: file is closed")
+
+// MappedFile is a wav file whose contents are memory mapped, giving zero
+// copy access to its PCM samples. The embedded WavFile is decoded from the
+// mapping, so its readers work as usual until Close, after which they
+// return errors.
+type MappedFile struct {
+	*WavFile
+
+	data []byte // the whole mapping
+	pcm  []byte
+}
+
+// OpenMapped maps the named file into memory and decodes its header. Slices
+// returned by the MappedFile must not be used after Close. Memory mapping is
+// only supported on Linux.
+func OpenMapped(name string) (*MappedFile, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if fi.Size() < RIFFHdrSize {
+		return nil, fmt.Errorf("wav: %s: file too small", name)
+	}
+	data, err := mmap(f, fi.Size())
+	if err != nil {
+		return nil, fmt.Errorf("wav: mmap %s: %w", name, err)
+	}
+	m := &MappedFile{data: data}
+	if m.WavFile, err = Decode(io.NewSectionReader(mapping{m}, 0, int64(len(data)))); err != nil {
+		munmap(data)
+		return nil, err
+	}
+	off := m.Data.off
+	end := min(off+int64(m.Data.SubChunkSize), int64(len(data)))
+	m.pcm = data[off:end:end]
+	return m, nil
+}
+
+// PCM returns the raw PCM samples.
+func (m *MappedFile) PCM() []byte {
+	return m.pcm
+}
+
+// Int16 returns the samples of a 16-bit PCM file as []int16 without copying.
+// It fails if the samples aren't suitably aligned in memory or the host is
+// not little endian.
+func (m *MappedFile) Int16() ([]int16, error) {
+	if err := m.checkView(16); err != nil {
+		return nil, err
+	}
+	return unsafe.Slice((*int16)(unsafe.Pointer(unsafe.SliceData(m.pcm))), len(m.pcm)/2), nil
+}
+
+// Int32 is like Int16 for 32-bit PCM files.
+func (m *MappedFile) Int32() ([]int32, error) {
+	if err := m.checkView(32); err != nil {
+		return nil, err
+	}
+	return unsafe.Slice((*int32)(unsafe.Pointer(unsafe.SliceData(m.pcm))), len(m.pcm)/4), nil
+}
+
+func (m *MappedFile) checkView(nbits int) error {
+	if m.pcm == nil {
+		return errClosed
+	}
+	if m.Fmt.Format() != FormatPCM || int(m.Fmt.BitsPerSample) != nbits {
+		return fmt.Errorf("wav: not a %d-bit PCM file", nbits)
+	}
+	if !littleEndian() {
+		return errors.New("wav: typed views need a little endian host")
+	}
+	if len(m.pcm) > 0 && uintptr(unsafe.Pointer(&m.pcm[0]))%uintptr(nbits/8) != 0 {
+		return fmt.Errorf("wav: PCM data is not %d-byte aligned", nbits/8)
+	}
+	return nil
+}
+
+func littleEndian() bool {
+	x := uint16(1)
+	return *(*byte)(unsafe.Pointer(&x)) == 1
+}
+
+// Close unmaps the file. All slices returned by m become invalid and the
+// WavFile readers fail.
+func (m *MappedFile) Close() error {
+	if m.data == nil {
+		return nil
+	}
+	data := m.data
+	m.data, m.pcm = nil, nil
+	return munmap(data)
+}
+
+// mapping reads the mapping of a MappedFile, failing once it is unmapped
+// instead of touching the released memory.
+type mapping struct {
+	m *MappedFile
+}
+
+func (r mapping) ReadAt(p []byte, off int64) (int, error) {
+	data := r.m.data
+	if data == nil {
+		return 0, errClosed
+	}
+	if off < 0 {
+		return 0, errors.New("wav: negative offset")
+	}
+	if off >= int64(len(data)) {
+		return 0, io.EOF
+	}
+	n := copy(p, data[off:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
@@ -0,0 +1,104 @@
+package wav
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"testing"
+)
+
+func TestOpenMapped(t *testing.T) {
+	name := tempWav(t, mergeBytes(t, parts...))
+	m, err := OpenMapped(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	want := mergeBytes(t, "datachunk.golden")[8:]
+	if !bytes.Equal(m.PCM(), want) {
+		t.Fatal("PCM() differs from the data chunk")
+	}
+	if m.List == nil {
+		t.Error("LIST chunk not decoded")
+	}
+	s, err := m.Int16()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s) != len(want)/2 || s[1] != 0x0fd4 {
+		t.Errorf("Int16() = len %d, s[1] = %#x", len(s), s[1])
+	}
+	if _, err := m.Int32(); err == nil {
+		t.Error("Int32() of a 16-bit file succeeded")
+	}
+	// readers of the embedded WavFile see the mapping
+	pcm, err := io.ReadAll(m.Data.NewReader())
+	if err != nil || !bytes.Equal(pcm, want) {
+		t.Errorf("NewReader() = %d bytes, %v", len(pcm), err)
+	}
+
+	if err := m.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Close(); err != nil {
+		t.Errorf("second Close() = %v", err)
+	}
+	if _, err := m.Int16(); err == nil {
+		t.Error("Int16() after Close succeeded")
+	}
+	if _, err := m.Data.PCMReader().Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.ReadAll(m.Data.PCMReader()); err == nil {
+		t.Error("reading the PCM reader after Close succeeded")
+	}
+	if _, err := io.ReadAll(m.Data.NewReader()); err == nil {
+		t.Error("NewReader() after Close succeeded")
+	}
+}
+
+func TestOpenMappedInt32(t *testing.T) {
+	f := floatFmt(8000, 2, 32)
+	f.AudioFormat = FormatPCM
+	src := []float64{0.5, -0.5, 0.25, -1}
+	w := tempFile(t)
+	wf, err := CreateFmt(w, f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fw, err := wf.FrameWriter()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fw.WriteFrames(src); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wf.Encode(w); err != nil {
+		t.Fatal(err)
+	}
+	m, err := OpenMapped(w.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	s, err := m.Int32()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []int32{1 << 30, -1 << 30, 1 << 29, -1 << 31}
+	for i := range want {
+		if s[i] != want[i] {
+			t.Errorf("Int32()[%d] = %#x, want %#x", i, s[i], want[i])
+		}
+	}
+}
+
+func TestOpenMappedErrors(t *testing.T) {
+	if _, err := OpenMapped("does-not-exist.wav"); !os.IsNotExist(err) {
+		t.Errorf("OpenMapped(missing) = %v", err)
+	}
+	if _, err := OpenMapped(tempWav(t, []byte("RIFF"))); err == nil {
+		t.Error("OpenMapped(short file) succeeded")
+	}
+}
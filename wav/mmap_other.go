@@ -0,0 +1,18 @@
+//go:build !linux
+
+package wav
+
+import (
+	"errors"
+	"os"
+)
+
+var errNoMmap = errors.New("memory mapping is not supported on this platform")
+
+func mmap(f *os.File, size int64) ([]byte, error) {
+	return nil, errNoMmap
+}
+
+func munmap(b []byte) error {
+	return errNoMmap
+}
@@ -0,0 +1,170 @@
+package wav
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Matrix is a channel mixing matrix. Matrix[o][i] is the gain applied to
+// input channel i when producing output channel o.
+type Matrix [][]float64
+
+// ExtractMatrix returns a matrix which picks the given channels of an
+// nchans channel input in the given order. It can be used to extract a
+// subset of channels as well as to reorder them.
+func ExtractMatrix(nchans int, chans ...int) (Matrix, error) {
+	m := make(Matrix, len(chans))
+	for o, c := range chans {
+		if c < 0 || c >= nchans {
+			return nil, fmt.Errorf("wav: no channel %d in a %d channel input", c, nchans)
+		}
+		m[o] = make([]float64, nchans)
+		m[o][c] = 1
+	}
+	return m, nil
+}
+
+// DownmixMatrix returns a matrix which averages all nchans channels into a
+// single mono channel.
+func DownmixMatrix(nchans int) Matrix {
+	row := make([]float64, nchans)
+	for i := range row {
+		row[i] = 1 / float64(nchans)
+	}
+	return Matrix{row}
+}
+
+// Apply mixes the interleaved frames in src into dst. dst must have room for
+// len(src)/len(m[0]) frames of len(m) channels. An empty matrix mixes
+// nothing.
+func (m Matrix) Apply(dst, src []float64) {
+	if len(m) == 0 || len(m[0]) == 0 {
+		return
+	}
+	in, out := len(m[0]), len(m)
+	for f := 0; f < len(src)/in; f++ {
+		frame := src[f*in : (f+1)*in]
+		for o, row := range m {
+			var s float64
+			for i, g := range row {
+				s += g * frame[i]
+			}
+			dst[f*out+o] = s
+		}
+	}
+}
+
+func (m Matrix) validate(nchans int) error {
+	if len(m) == 0 {
+		return errors.New("wav: empty channel matrix")
+	}
+	for o, row := range m {
+		if len(row) != nchans {
+			return fmt.Errorf("wav: matrix row %d has %d gains, want %d", o, len(row), nchans)
+		}
+	}
+	return nil
+}
+
+// channelMask derives the speaker mask of the output channels. It is only
+// known when every output channel is a copy of a single input channel and
+// the channels keep the mask's ascending order; otherwise the mask is left
+// unspecified (zero).
+func (m Matrix) channelMask(in uint32) uint32 {
+	var out uint32
+	for _, row := range m {
+		src := -1
+		for i, g := range row {
+			if g == 0 {
+				continue
+			}
+			if g != 1 || src != -1 {
+				return 0
+			}
+			src = i
+		}
+		bit := speakerBit(in, src)
+		if bit <= out {
+			return 0
+		}
+		out |= bit
+	}
+	return out
+}
+
+// speakerBit returns the mask bit assigned to the nth channel.
+func speakerBit(mask uint32, n int) uint32 {
+	if n < 0 {
+		return 0
+	}
+	for bit := uint32(1); bit != 0; bit <<= 1 {
+		if mask&bit == 0 {
+			continue
+		}
+		if n == 0 {
+			return bit
+		}
+		n--
+	}
+	return 0
+}
+
+// Remix applies the channel matrix m to every frame of src and writes the
+// result as a new wav file into w. The output keeps the sample format of src
+// while NumChans, BlockAlign, ByteRate and, for extensible files,
+// ChannelMask are adjusted to the new channel layout.
+func Remix(w io.WriteSeeker, src *WavFile, m Matrix) error {
+	inChans := int(src.Fmt.NumChans)
+	if err := m.validate(inChans); err != nil {
+		return err
+	}
+	if r := src.Data.PCMReader(); r != nil {
+		if _, err := r.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+	}
+	fr, err := src.FrameReader()
+	if err != nil {
+		return err
+	}
+
+	f := src.Fmt
+	outChans := len(m)
+	width := int(f.BlockAlign) / inChans
+	f.NumChans = uint16(outChans)
+	f.BlockAlign = uint16(outChans * width)
+	f.ByteRate = f.SampleRate * uint32(f.BlockAlign)
+	if f.AudioFormat == FormatExtensible {
+		f.ChannelMask = m.channelMask(src.Fmt.ChannelMask)
+	}
+	dst, err := CreateFmt(w, f)
+	if err != nil {
+		return err
+	}
+	fw, err := dst.FrameWriter()
+	if err != nil {
+		return err
+	}
+
+	const frames = 4096
+	in := make([]float64, frames*inChans)
+	out := make([]float64, frames*outChans)
+	for {
+		n, err := fr.ReadFrames(in)
+		if n > 0 {
+			m.Apply(out[:n*outChans], in[:n*inChans])
+			if _, err := fw.WriteFrames(out[:n*outChans]); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	_, err = dst.Encode(w)
+	return err
+}
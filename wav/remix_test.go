@@ -0,0 +1,157 @@
+package wav
+
+import (
+	"io"
+	"testing"
+)
+
+func extractMatrix(t *testing.T, nchans int, chans ...int) Matrix {
+	t.Helper()
+	m, err := ExtractMatrix(nchans, chans...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func writeFrames(t *testing.T, f FmtChunk, frames []float64) *WavFile {
+	t.Helper()
+	w := tempFile(t)
+	wf, err := CreateFmt(w, f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fw, err := wf.FrameWriter()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fw.WriteFrames(frames); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wf.Encode(w); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	wf, err = Decode(w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return wf
+}
+
+func readFrames(t *testing.T, wf *WavFile) []float64 {
+	t.Helper()
+	fr, err := wf.FrameReader()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := make([]float64, 1024)
+	n, err := fr.ReadFrames(p)
+	if err != nil && err != io.EOF {
+		t.Fatal(err)
+	}
+	return p[:n*int(wf.Fmt.NumChans)]
+}
+
+func TestRemix(t *testing.T) {
+	src := []float64{
+		0.5, 0.25, -0.5, 0,
+		0.25, -0.25, 0.125, 0.5,
+	}
+	tests := []struct {
+		name string
+		m    Matrix
+		want []float64
+	}{
+		{"extract", extractMatrix(t, 4, 2), []float64{-0.5, 0.125}},
+		{"reorder", extractMatrix(t, 4, 1, 0), []float64{0.25, 0.5, -0.25, 0.25}},
+		{"downmix", DownmixMatrix(4), []float64{0.0625, 0.15625}},
+		{"gains", Matrix{{0.5, 0.5, 0, 0}, {0, 0, 2, 0}}, []float64{0.375, -1, 0, 0.25}},
+	}
+	for _, tt := range tests {
+		in := writeFrames(t, floatFmt(8000, 4, 32), src)
+		out := tempFile(t)
+		if err := Remix(out, in, tt.m); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if _, err := out.Seek(0, io.SeekStart); err != nil {
+			t.Fatal(err)
+		}
+		wf, err := Decode(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		nchans := len(tt.m)
+		if wf.Fmt.NumChans != uint16(nchans) ||
+			wf.Fmt.BlockAlign != uint16(4*nchans) ||
+			wf.Fmt.ByteRate != uint32(8000*4*nchans) {
+			t.Errorf("%s: bad header: %+v", tt.name, wf.Fmt)
+		}
+		got := readFrames(t, wf)
+		if len(got) != len(tt.want) {
+			t.Fatalf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestRemixChannelMask(t *testing.T) {
+	f := floatFmt(8000, 6, 16)
+	f.AudioFormat = FormatExtensible
+	f.SubChunkSize = 40
+	f.CbSize = 22
+	f.ValidBitsPerSample = 16
+	f.ChannelMask = 0x3f // 5.1
+	f.SubFormat = SubFormatPCM
+	in := writeFrames(t, f, make([]float64, 12))
+
+	tests := []struct {
+		m    Matrix
+		want uint32
+	}{
+		{extractMatrix(t, 6, 0, 1), 0x3},
+		{extractMatrix(t, 6, 2), 0x4},
+		{extractMatrix(t, 6, 1, 0), 0}, // order no longer matches the mask
+		{DownmixMatrix(6), 0},
+	}
+	for _, tt := range tests {
+		out := tempFile(t)
+		if err := Remix(out, in, tt.m); err != nil {
+			t.Fatal(err)
+		}
+		out.Seek(0, io.SeekStart)
+		wf, err := Decode(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if wf.Fmt.ChannelMask != tt.want {
+			t.Errorf("ChannelMask = %#x, want %#x", wf.Fmt.ChannelMask, tt.want)
+		}
+		if wf.Data.SubChunkSize != uint32(2*2*len(tt.m)) {
+			t.Errorf("data size = %d", wf.Data.SubChunkSize)
+		}
+	}
+}
+
+func TestRemixBadMatrix(t *testing.T) {
+	in := writeFrames(t, floatFmt(8000, 2, 32), make([]float64, 4))
+	if err := Remix(tempFile(t), in, Matrix{{1, 0, 0}}); err == nil {
+		t.Error("expected an error for mismatched matrix")
+	}
+	// an empty matrix mixes nothing
+	Matrix{}.Apply(nil, []float64{1, 2})
+	Matrix{{}}.Apply(nil, []float64{1, 2})
+
+	for _, c := range []int{2, -1} {
+		if _, err := ExtractMatrix(2, 0, c); err == nil {
+			t.Errorf("ExtractMatrix(2, 0, %d): got no error", c)
+		}
+	}
+}
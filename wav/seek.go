@@ -0,0 +1,116 @@
+package wav
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// NumFrames returns the number of complete frames in the data chunk.
+func (wf *WavFile) NumFrames() int64 {
+	if wf.Fmt.BlockAlign == 0 {
+		return 0
+	}
+	return int64(wf.Data.SubChunkSize) / int64(wf.Fmt.BlockAlign)
+}
+
+// DurationFrames returns the index of the frame playing at d, that is d
+// converted to frames and rounded down.
+func (wf *WavFile) DurationFrames(d time.Duration) int64 {
+	rate := int64(wf.Fmt.SampleRate)
+	sec, frac := int64(d/time.Second), int64(d%time.Second)
+	return sec*rate + frac*rate/int64(time.Second)
+}
+
+// FrameDuration returns the start time of the given frame.
+func (wf *WavFile) FrameDuration(frame int64) time.Duration {
+	rate := int64(wf.Fmt.SampleRate)
+	if rate == 0 {
+		return 0
+	}
+	sec, rem := frame/rate, frame%rate
+	return time.Duration(sec)*time.Second + time.Duration(rem)*time.Second/time.Duration(rate)
+}
+
+// SeekFrame sets the position of the PCM reader to the given frame,
+// interpreted according to whence as in io.Seeker, and returns the new
+// position in frames. The position always lands on a frame boundary; a
+// position left in the middle of a frame by a byte level seek is rounded
+// down first. Seeking outside [0, NumFrames] is an error.
+func (wf *WavFile) SeekFrame(frame int64, whence int) (int64, error) {
+	r := wf.Data.PCMReader()
+	if r == nil {
+		return 0, errors.New("wav: nil PCM reader")
+	}
+	var base int64
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		pos, err := wf.FramePos()
+		if err != nil {
+			return 0, err
+		}
+		base = pos
+	case io.SeekEnd:
+		base = wf.NumFrames()
+	default:
+		return 0, errors.New("wav: invalid whence")
+	}
+	pos := base + frame
+	if pos < 0 || pos > wf.NumFrames() {
+		return 0, fmt.Errorf("wav: frame %d out of range [0, %d]", pos, wf.NumFrames())
+	}
+	if _, err := r.Seek(pos*int64(wf.Fmt.BlockAlign), io.SeekStart); err != nil {
+		return 0, err
+	}
+	return pos, nil
+}
+
+// SeekTime seeks to the frame playing at d and returns its index.
+func (wf *WavFile) SeekTime(d time.Duration) (int64, error) {
+	if d < 0 {
+		return 0, fmt.Errorf("wav: negative time: %s", d)
+	}
+	return wf.SeekFrame(wf.DurationFrames(d), io.SeekStart)
+}
+
+// FramePos returns the current position of the PCM reader in frames.
+func (wf *WavFile) FramePos() (int64, error) {
+	r := wf.Data.PCMReader()
+	if r == nil {
+		return 0, errors.New("wav: nil PCM reader")
+	}
+	if wf.Fmt.BlockAlign == 0 {
+		return 0, errors.New("wav: zero block align")
+	}
+	off, err := r.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, err
+	}
+	return off / int64(wf.Fmt.BlockAlign), nil
+}
+
+// ReadFrameRange seeks to frame start and returns the interleaved samples of
+// the frames in [start, end). The PCM reader is left at end.
+func (wf *WavFile) ReadFrameRange(start, end int64) ([]float64, error) {
+	if start > end || end > wf.NumFrames() {
+		return nil, fmt.Errorf("wav: invalid frame range [%d, %d)", start, end)
+	}
+	if _, err := wf.SeekFrame(start, io.SeekStart); err != nil {
+		return nil, err
+	}
+	fr, err := wf.FrameReader()
+	if err != nil {
+		return nil, err
+	}
+	nchans := int(wf.Fmt.NumChans)
+	p := make([]float64, int(end-start)*nchans)
+	if _, err := ReadFullFrames(fr, p, nchans); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return p, nil
+}
@@ -0,0 +1,119 @@
+package wav
+
+import (
+	"fmt"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestSeekFrame(t *testing.T) {
+	f := floatFmt(1000, 2, 24)
+	f.AudioFormat = FormatPCM
+	src := make([]float64, 20) // 10 frames
+	for i := range src {
+		src[i] = float64(i) / 32
+	}
+	wf := writeFrames(t, f, src)
+	if n := wf.NumFrames(); n != 10 {
+		t.Fatalf("NumFrames() = %d, want 10", n)
+	}
+
+	tests := []struct {
+		frame  int64
+		whence int
+		want   int64
+	}{
+		{3, io.SeekStart, 3},
+		{2, io.SeekCurrent, 5},
+		{-1, io.SeekEnd, 9},
+		{-9, io.SeekCurrent, 0},
+		{0, io.SeekEnd, 10},
+	}
+	for _, tt := range tests {
+		pos, err := wf.SeekFrame(tt.frame, tt.whence)
+		if err != nil || pos != tt.want {
+			t.Fatalf("SeekFrame(%d, %d) = %d, %v, want %d", tt.frame, tt.whence, pos, err, tt.want)
+		}
+	}
+	for _, frame := range []int64{-1, 11} {
+		if _, err := wf.SeekFrame(frame, io.SeekStart); err == nil {
+			t.Errorf("SeekFrame(%d) succeeded", frame)
+		}
+	}
+
+	// a byte level seek into the middle of a frame is realigned
+	if _, err := wf.Data.PCMReader().Seek(4*6+4, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	if pos, err := wf.FramePos(); err != nil || pos != 4 {
+		t.Fatalf("FramePos() = %d, %v, want 4", pos, err)
+	}
+	if pos, err := wf.SeekFrame(1, io.SeekCurrent); err != nil || pos != 5 {
+		t.Fatalf("SeekFrame(1, SeekCurrent) = %d, %v, want 5", pos, err)
+	}
+	if got := readFrames(t, wf)[:2]; fmt.Sprint(got) != fmt.Sprint(src[10:12]) {
+		t.Errorf("frame 5 = %v, want %v", got, src[10:12])
+	}
+
+	// a malformed header
+	wf.Fmt.BlockAlign = 0
+	if _, err := wf.SeekFrame(0, io.SeekCurrent); err == nil {
+		t.Error("SeekFrame(0, SeekCurrent) with a zero block align succeeded")
+	}
+}
+
+func TestSeekTime(t *testing.T) {
+	f := floatFmt(44100, 1, 16)
+	f.AudioFormat = FormatPCM
+	wf := writeFrames(t, f, make([]float64, 44100))
+	tests := []struct {
+		d    time.Duration
+		want int64
+	}{
+		{0, 0},
+		{500 * time.Millisecond, 22050},
+		{time.Second / 44100, 0}, // 22.675µs rounds down
+		{23 * time.Microsecond, 1},
+		{time.Second, 44100},
+	}
+	for _, tt := range tests {
+		pos, err := wf.SeekTime(tt.d)
+		if err != nil || pos != tt.want {
+			t.Errorf("SeekTime(%s) = %d, %v, want %d", tt.d, pos, err, tt.want)
+		}
+	}
+	if _, err := wf.SeekTime(-time.Millisecond); err == nil {
+		t.Error("SeekTime(-1ms) succeeded")
+	}
+	if d := wf.FrameDuration(22050); d != 500*time.Millisecond {
+		t.Errorf("FrameDuration(22050) = %s", d)
+	}
+}
+
+func TestReadFrameRange(t *testing.T) {
+	f := floatFmt(8000, 3, 32)
+	src := make([]float64, 30)
+	for i := range src {
+		src[i] = float64(i) / 64
+	}
+	wf := writeFrames(t, f, src)
+	got, err := wf.ReadFrameRange(2, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(got) != fmt.Sprint(src[6:15]) {
+		t.Errorf("ReadFrameRange(2, 5) = %v, want %v", got, src[6:15])
+	}
+	if pos, _ := wf.FramePos(); pos != 5 {
+		t.Errorf("FramePos() = %d, want 5", pos)
+	}
+	if got, err := wf.ReadFrameRange(10, 10); err != nil || len(got) != 0 {
+		t.Errorf("ReadFrameRange(10, 10) = %v, %v", got, err)
+	}
+	for _, r := range [][2]int64{{5, 4}, {0, 11}, {-1, 2}} {
+		if _, err := wf.ReadFrameRange(r[0], r[1]); err == nil {
+			t.Errorf("ReadFrameRange(%d, %d) succeeded", r[0], r[1])
+		}
+	}
+}
@@ -3,6 +3,7 @@ package wav
 // http://soundfile.sapp.org/doc/WaveFormat
 // https://ccrma.stanford.edu/courses/422-winter-2014/projects/WaveFormat/
 import (
+	"bufio"
 	"encoding/binary"
 	"errors"
 	"fmt"
@@ -19,6 +20,12 @@ var (
 	INFO = [4]byte{'I', 'N', 'F', 'O'}
 	FMT  = [4]byte{'f', 'm', 't', ' '}
 	DATA = [4]byte{'d', 'a', 't', 'a'}
+	CUE  = [4]byte{'c', 'u', 'e', ' '}
+	FACT = [4]byte{'f', 'a', 'c', 't'}
+
+	// SubFormat GUIDs used by WAVE_FORMAT_EXTENSIBLE files.
+	SubFormatPCM       = [16]byte{0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x10, 0x00, 0x80, 0x00, 0x00, 0xaa, 0x00, 0x38, 0x9b, 0x71}
+	SubFormatIEEEFloat = [16]byte{0x03, 0x00, 0x00, 0x00, 0x00, 0x00, 0x10, 0x00, 0x80, 0x00, 0x00, 0xaa, 0x00, 0x38, 0x9b, 0x71}
 
 	// Copied from go-audio
 	// List of wav chunk names
@@ -47,6 +54,7 @@ type WavFile struct {
 	Fmt  FmtChunk
 	Data DataChunk
 	List *ListChunk
+	Cue  *CueChunk
 
 	rifWr io.Writer
 	fmtWr io.Writer
@@ -59,6 +67,13 @@ const (
 	DataChunkHdrSize = 8 // not including PCM samples
 )
 
+// Audio format tags
+const (
+	FormatPCM        = 0x0001
+	FormatIEEEFloat  = 0x0003
+	FormatExtensible = 0xfffe
+)
+
 func (wf *WavFile) writeHdr(w io.Writer) error {
 	if wf.rifWr != nil {
 		w = wf.rifWr
@@ -99,12 +114,24 @@ func (wf *WavFile) Encode(w io.WriteSeeker) (int64, error) {
 		return 0, err
 	}
 
-	off := wf.Data.size() + RIFFHdrSize + FmtChunkSize
-	if wf.List != nil {
-		// forward to List chunk
-		if _, err := w.Seek(off, io.SeekStart); err != nil {
+	off := wf.Data.size() + RIFFHdrSize + wf.Fmt.size()
+	if _, err := w.Seek(off, io.SeekStart); err != nil {
+		return 0, err
+	}
+	if wf.Data.SubChunkSize&1 != 0 {
+		// chunks are word aligned, pad the odd sized audio
+		if _, err := w.Write([]byte{0}); err != nil {
+			return 0, err
+		}
+		off++
+	}
+	if wf.Cue != nil {
+		if err := wf.Cue.Pack(w); err != nil {
 			return 0, err
 		}
+		off += wf.Cue.size()
+	}
+	if wf.List != nil {
 		if err := wf.List.Pack(w); err != nil {
 			return 0, err
 		}
@@ -120,33 +147,40 @@ func (wf *WavFile) Encode(w io.WriteSeeker) (int64, error) {
 
 func Create(w io.WriteSeeker, sampleRate, nchans, nbits int) (*WavFile, error) {
 	blockAlign := uint16(nchans * nbits / 8)
+	return CreateFmt(w, FmtChunk{
+		SubChunkID:    FMT,
+		SubChunkSize:  0x10,
+		AudioFormat:   FormatPCM,
+		NumChans:      uint16(nchans),
+		SampleRate:    uint32(sampleRate),
+		ByteRate:      uint32(sampleRate) * uint32(blockAlign),
+		BlockAlign:    blockAlign,
+		BitsPerSample: uint16(nbits),
+	})
+}
+
+// CreateFmt is like Create but takes a complete fmt chunk, which allows
+// creating IEEE float and WAVE_FORMAT_EXTENSIBLE files.
+func CreateFmt(w io.WriteSeeker, f FmtChunk) (*WavFile, error) {
+	fmtSize := f.size()
 	wf := &WavFile{
 		Hdr: RIFFHdr{
 			ChunkID:   RIFF,
-			ChunkSize: RIFFHdrSize + FmtChunkSize + DataChunkHdrSize - 8,
+			ChunkSize: uint32(RIFFHdrSize+fmtSize+DataChunkHdrSize) - 8,
 			Fmt:       WAVE,
 		},
-		Fmt: FmtChunk{
-			SubChunkID:    FMT,
-			SubChunkSize:  0x10,
-			AudioFormat:   1,
-			NumChans:      uint16(nchans),
-			SampleRate:    uint32(sampleRate),
-			ByteRate:      uint32(sampleRate) * uint32(blockAlign),
-			BlockAlign:    blockAlign,
-			BitsPerSample: uint16(nbits),
-		},
+		Fmt: f,
 		Data: DataChunk{
 			SubChunkID:   DATA,
 			SubChunkSize: 0,
 		},
 		rifWr: sectionWriter(w, 0, RIFFHdrSize),
-		fmtWr: sectionWriter(w, RIFFHdrSize, FmtChunkSize),
-		datWr: sectionWriter(w, RIFFHdrSize+FmtChunkSize, DataChunkHdrSize),
+		fmtWr: sectionWriter(w, RIFFHdrSize, fmtSize),
+		datWr: sectionWriter(w, RIFFHdrSize+fmtSize, DataChunkHdrSize),
 	}
 
-	// forward to PCM offset: 44
-	var off int64 = RIFFHdrSize + FmtChunkSize + DataChunkHdrSize
+	// forward to PCM offset: 44 for plain PCM files
+	off := RIFFHdrSize + fmtSize + DataChunkHdrSize
 	if _, err := w.Seek(off, io.SeekStart); err != nil {
 		return nil, err
 	}
@@ -165,8 +199,26 @@ func Decode(r io.ReadSeeker) (*WavFile, error) {
 	if err := w.Fmt.Unpack(r); err != nil {
 		return nil, err
 	}
-	if err := w.Data.Unpack(r); err != nil {
-		return nil, err
+	for {
+		if err := w.Data.Unpack(r); err != nil {
+			return nil, err
+		}
+		if w.Data.SubChunkID == DATA {
+			break
+		}
+		// metadata may come before the audio too, e.g. as written by
+		// ffmpeg. Other chunks (e.g. fact) are skipped.
+		off, err := r.Seek(-DataChunkHdrSize, io.SeekCurrent)
+		if err != nil {
+			return nil, err
+		}
+		if err := w.decodeChunk(r, off, w.Data.SubChunkID); err != nil {
+			return nil, err
+		}
+		size := int64(w.Data.SubChunkSize)
+		if _, err := r.Seek(off+8+size+size&1, io.SeekStart); err != nil {
+			return nil, err
+		}
 	}
 	var (
 		curOff int64
@@ -178,26 +230,82 @@ func Decode(r io.ReadSeeker) (*WavFile, error) {
 	}
 
 	w.Data.pcmRd = sectionReader(r, curOff, int64(w.Data.SubChunkSize))
+	w.Data.off = curOff
+	if ra, ok := r.(io.ReaderAt); ok {
+		w.Data.ra = ra
+	}
 
 	if endOff, err = r.Seek(0, io.SeekEnd); err != nil {
 		return nil, err
 	}
-	if curOff+int64(w.Data.SubChunkSize) >= endOff {
-		return w, nil // no list chunks available
-	}
 	curOff = curOff + int64(w.Data.SubChunkSize)
-	if _, err := r.Seek(curOff, io.SeekStart); err != nil {
-		return nil, err
+	if w.Data.SubChunkSize&1 != 0 && curOff < endOff {
+		// skip the pad byte, if written. Chunk IDs never start with a NUL.
+		if _, err := r.Seek(curOff, io.SeekStart); err != nil {
+			return nil, err
+		}
+		var pad [1]byte
+		if _, err := io.ReadFull(r, pad[:]); err != nil {
+			return nil, err
+		}
+		if pad[0] == 0 {
+			curOff++
+		}
 	}
-	lck := &ListChunk{}
-	if err := lck.Unpack(r); err != nil {
-		return nil, err
+	for curOff+8 <= endOff {
+		if _, err := r.Seek(curOff, io.SeekStart); err != nil {
+			return nil, err
+		}
+		var hdr [8]byte
+		if _, err := io.ReadFull(r, hdr[:]); err != nil {
+			return nil, err
+		}
+		if err := w.decodeChunk(r, curOff, [4]byte(hdr[:4])); err != nil {
+			return nil, err
+		}
+		size := int64(binary.LittleEndian.Uint32(hdr[4:]))
+		curOff += 8 + size + size&1
 	}
-	w.List = lck
 
 	return w, nil
 }
 
+// decodeChunk decodes the chunk with the given id at offset off of r if it
+// is a LIST/INFO or cue chunk, and ignores it otherwise.
+func (w *WavFile) decodeChunk(r io.ReadSeeker, off int64, id [4]byte) error {
+	switch id {
+	case LIST:
+		if _, err := r.Seek(off+8, io.SeekStart); err != nil {
+			return err
+		}
+		var typ [4]byte
+		if _, err := io.ReadFull(r, typ[:]); err != nil {
+			return err
+		}
+		if typ != INFO {
+			return nil // some other kind of list, e.g. adtl
+		}
+		if _, err := r.Seek(off, io.SeekStart); err != nil {
+			return err
+		}
+		lck := &ListChunk{}
+		if err := lck.Unpack(r); err != nil {
+			return err
+		}
+		w.List = lck
+	case CUE:
+		if _, err := r.Seek(off, io.SeekStart); err != nil {
+			return err
+		}
+		cue := &CueChunk{}
+		if err := cue.Unpack(r); err != nil {
+			return err
+		}
+		w.Cue = cue
+	}
+	return nil
+}
+
 type RIFFHdr struct {
 	ChunkID   [4]byte // RIFF
 	ChunkSize uint32
@@ -241,9 +349,32 @@ type FmtChunk struct {
 	ByteRate      uint32 // avg bytes per sec
 	BlockAlign    uint16
 	BitsPerSample uint16
+
+	// Only present when SubChunkSize is greater than 16. The rest of
+	// the fields are used by WAVE_FORMAT_EXTENSIBLE files.
+	CbSize             uint16
+	ValidBitsPerSample uint16
+	ChannelMask        uint32
+	SubFormat          [16]byte
+}
+
+func (f *FmtChunk) size() int64 {
+	return int64(f.SubChunkSize + 8)
+}
+
+// Format returns the effective audio format tag. For WAVE_FORMAT_EXTENSIBLE
+// files it is taken from the first two bytes of SubFormat.
+func (f *FmtChunk) Format() uint16 {
+	if f.AudioFormat == FormatExtensible {
+		return binary.LittleEndian.Uint16(f.SubFormat[:2])
+	}
+	return f.AudioFormat
 }
 
 func (f *FmtChunk) Pack(w io.Writer) error {
+	if need := f.extensionSize(); f.SubChunkSize > 16 && f.SubChunkSize < need {
+		return fmt.Errorf("wav: fmt chunk size %d too small for cbSize %d", f.SubChunkSize, f.CbSize)
+	}
 	ew := &errWriter{w: w}
 	p := make([]byte, 4)
 
@@ -266,9 +397,33 @@ func (f *FmtChunk) Pack(w io.Writer) error {
 	binary.LittleEndian.PutUint16(p[:2], f.BitsPerSample)
 	ew.write(p[:2])
 
+	if f.SubChunkSize <= 16 {
+		return ew.err
+	}
+	binary.LittleEndian.PutUint16(p[:2], f.CbSize)
+	ew.write(p[:2])
+	if f.CbSize >= 22 {
+		binary.LittleEndian.PutUint16(p[:2], f.ValidBitsPerSample)
+		ew.write(p[:2])
+		binary.LittleEndian.PutUint32(p, f.ChannelMask)
+		ew.write(p)
+		ew.write(f.SubFormat[:])
+	}
+	if written := f.extensionSize(); written < f.SubChunkSize {
+		ew.write(make([]byte, f.SubChunkSize-written))
+	}
 	return ew.err
 }
 
+// extensionSize returns the size of the fmt chunk body up to the end of the
+// extension written for CbSize.
+func (f *FmtChunk) extensionSize() uint32 {
+	if f.CbSize >= 22 {
+		return 40
+	}
+	return 18
+}
+
 func (f *FmtChunk) Unpack(r io.Reader) error {
 	er := &errReader{r: r}
 	p := make([]byte, 4)
@@ -295,6 +450,24 @@ func (f *FmtChunk) Unpack(r io.Reader) error {
 	er.ReadFull(p[:2]) // BitsPerSample
 	f.BitsPerSample = binary.LittleEndian.Uint16(p[:2])
 
+	if f.SubChunkSize <= 16 {
+		return er.err
+	}
+	read := uint32(18)
+	er.ReadFull(p[:2]) // CbSize
+	f.CbSize = binary.LittleEndian.Uint16(p[:2])
+	if f.CbSize >= 22 && f.SubChunkSize >= 40 {
+		er.ReadFull(p[:2]) // ValidBitsPerSample
+		f.ValidBitsPerSample = binary.LittleEndian.Uint16(p[:2])
+		er.ReadFull(p) // ChannelMask
+		f.ChannelMask = binary.LittleEndian.Uint32(p)
+		er.ReadFull(f.SubFormat[:])
+		read += 22
+	}
+	if read < f.SubChunkSize {
+		// skip whatever is left of the extension
+		er.ReadFull(make([]byte, f.SubChunkSize-read))
+	}
 	return er.err
 }
 
@@ -304,6 +477,9 @@ type DataChunk struct {
 
 	pcmWr io.Writer
 	pcmRd io.ReadSeeker
+
+	ra  io.ReaderAt // source of decoded files
+	off int64       // offset of the PCM samples in the source
 }
 
 func (d *DataChunk) size() int64 {
@@ -330,10 +506,41 @@ func (d *DataChunk) Pack(w io.Writer) error {
 	return ew.err
 }
 
+// PCMReader returns the reader over the PCM samples. It is shared by every
+// user of the file and not safe for concurrent use; see NewReader.
 func (d *DataChunk) PCMReader() io.ReadSeeker {
 	return d.pcmRd
 }
 
+// NewReader returns a new reader over the PCM samples, positioned at the
+// start of the data chunk. Each reader keeps its own position, so readers
+// returned by separate calls can be used from different goroutines as long as
+// the decoded source supports concurrent ReadAt calls, as *os.File and
+// *bytes.Reader do. It returns nil if the file was not decoded from an
+// io.ReaderAt.
+func (d *DataChunk) NewReader() *io.SectionReader {
+	if d.ra == nil {
+		return nil
+	}
+	return io.NewSectionReader(d.ra, d.off, int64(d.SubChunkSize))
+}
+
+// ReaderAt returns an io.ReaderAt over the PCM samples, with offset 0 at the
+// start of the data chunk. It is safe for concurrent use under the same
+// conditions as NewReader and returns nil in the same cases.
+func (d *DataChunk) ReaderAt() io.ReaderAt {
+	if r := d.NewReader(); r != nil {
+		return r
+	}
+	return nil
+}
+
+// Offset returns the offset of the PCM samples in the file the chunk was
+// decoded from.
+func (d *DataChunk) Offset() int64 {
+	return d.off
+}
+
 func (d *DataChunk) PCMWriter() io.Writer {
 	return d.pcmWr
 }
@@ -359,17 +566,23 @@ func (l *ListChunk) Unpack(r io.Reader) error {
 		return fmt.Errorf("wav: unsupported subchunk id: %v", string(l.SubChunkID[:]))
 	}
 
-	readBytes := uint32(0)
-	totalBytes := l.SubChunkSize - uint32(len(l.TypeID))
-	for readBytes < totalBytes {
+	if er.err != nil {
+		return er.err
+	}
+	// buffered for InfoChunk to look for pad bytes, limited to not read
+	// past the list
+	br := bufio.NewReader(io.LimitReader(r, int64(l.SubChunkSize)-int64(len(l.TypeID))))
+	for {
+		if _, err := br.Peek(1); err == io.EOF {
+			break
+		}
 		var ic InfoChunk
-		if err := ic.Unpack(r); err != nil {
+		if err := ic.Unpack(br); err != nil {
 			return fmt.Errorf("list.Unpack: %w", err)
 		}
 		l.SubChunks = append(l.SubChunks, ic)
-		readBytes += uint32(ic.RawSize())
 	}
-	return er.err
+	return nil
 }
 
 func (l *ListChunk) Pack(w io.Writer) error {
@@ -435,6 +648,20 @@ func (i *InfoChunk) Unpack(r io.Reader) error {
 
 	p = make([]byte, i.Size)
 	er.ReadFull(p)
+	if er.err != nil {
+		return er.err
+	}
+	// skip the pad of an odd sized chunk. Some writers (e.g. go-audio)
+	// leave it out, so a byte other than NUL is kept when r can unread it.
+	if i.Size&1 != 0 {
+		if bs, ok := r.(io.ByteScanner); ok {
+			if c, err := bs.ReadByte(); err == nil && c != 0 {
+				bs.UnreadByte()
+			}
+		} else if _, err := io.ReadFull(r, make([]byte, 1)); err != nil && err != io.EOF {
+			return err
+		}
+	}
 
 	// Throw away terminating NUL.
 	if i.Size > 0 && p[i.Size-1] == '\x00' {
@@ -452,15 +679,19 @@ func (i *InfoChunk) Pack(w io.Writer) error {
 	binary.LittleEndian.PutUint32(p, uint32(len(i.Text)+1))
 	ew.write(p)
 
-	i.Text = append(i.Text, '\x00') // NUL terminate
 	ew.write(i.Text)
+	ew.write([]byte{0}) // NUL terminate
+	if len(i.Text)&1 == 0 {
+		ew.write([]byte{0}) // pad to an even size
+	}
 
 	return ew.err
 }
 
 func (i *InfoChunk) RawSize() int {
-	// fields + txt size + NUL terminator
-	return 8 + len(i.Text) + 1
+	// fields + txt size + NUL terminator + pad
+	n := len(i.Text) + 1
+	return 8 + n + n&1
 }
 
 type errReader struct {
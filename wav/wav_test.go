@@ -3,12 +3,16 @@ package wav
 import (
 	"bytes"
 	"crypto/rand"
+	"encoding/binary"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"log"
 	"os"
+	"strings"
 	"testing"
 
+	gowav "github.com/go-audio/wav"
 	"github.com/siddontang/go/ioutil2"
 )
 
@@ -77,13 +81,119 @@ func TestPCMReader(t *testing.T) {
 	}
 }
 
+func TestNewReaderConcurrent(t *testing.T) {
+	pcm := mergeBytes(t, "datachunk.golden")[8:]
+	for _, open := range []func() io.ReadSeeker{
+		func() io.ReadSeeker { return mergeRead(t, parts...) },
+		func() io.ReadSeeker {
+			f, err := os.Open(tempWav(t, mergeBytes(t, parts...)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { f.Close() })
+			return f
+		},
+	} {
+		wf, err := Decode(open())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := wf.Data.Offset(); got != 44 {
+			t.Errorf("Offset() = %d, want 44", got)
+		}
+		const workers = 8
+		chunk := len(pcm) / workers
+		errc := make(chan error, 2*workers)
+		for i := 0; i < workers; i++ {
+			off := i * chunk
+			want := pcm[off : off+chunk]
+			go func() {
+				r := wf.Data.NewReader()
+				if _, err := r.Seek(int64(off), io.SeekStart); err != nil {
+					errc <- err
+					return
+				}
+				got := make([]byte, chunk)
+				if _, err := io.ReadFull(r, got); err != nil {
+					errc <- err
+					return
+				}
+				if !bytes.Equal(got, want) {
+					errc <- fmt.Errorf("NewReader: region at %d differs", off)
+					return
+				}
+				errc <- nil
+			}()
+			go func() {
+				got := make([]byte, chunk)
+				if _, err := wf.Data.ReaderAt().ReadAt(got, int64(off)); err != nil {
+					errc <- err
+					return
+				}
+				if !bytes.Equal(got, want) {
+					errc <- fmt.Errorf("ReaderAt: region at %d differs", off)
+					return
+				}
+				errc <- nil
+			}()
+		}
+		for i := 0; i < 2*workers; i++ {
+			if err := <-errc; err != nil {
+				t.Error(err)
+			}
+		}
+	}
+}
+
+func TestRangeFrameReader(t *testing.T) {
+	wf, err := Decode(mergeRead(t, parts...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := wf.NumFrames()
+	want, err := wf.ReadFrameRange(n/2, n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// moving the shared PCM reader must not affect the range reader
+	fr, err := wf.RangeFrameReader(n/2, n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wf.SeekFrame(1, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	got := make([]float64, len(want)+1)
+	m, err := fr.ReadFrames(got)
+	if err != nil || m != len(want) {
+		t.Fatalf("ReadFrames() = %d, %v, want %d", m, err, len(want))
+	}
+	if fmt.Sprint(got[:m]) != fmt.Sprint(want) {
+		t.Error("range reader samples differ")
+	}
+	if pos, _ := wf.FramePos(); pos != 1 {
+		t.Errorf("FramePos() = %d, want 1", pos)
+	}
+	if _, err := wf.RangeFrameReader(0, n+1); err == nil {
+		t.Error("RangeFrameReader past the end succeeded")
+	}
+}
+
+func tempWav(t *testing.T, b []byte) string {
+	f := tempFile(t)
+	if _, err := f.Write(b); err != nil {
+		t.Fatal(err)
+	}
+	return f.Name()
+}
+
 func TestCreate(t *testing.T) {
 	f, err := os.Create("new.wav")
 	if err != nil {
 		t.Fatal(err)
 	}
 	defer f.Close()
-	wf, err := Create(f, 7812)
+	wf, err := Create(f, 7812, 1, 16)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -186,6 +296,116 @@ func TestDecode(t *testing.T) {
 	}
 }
 
+func TestPadByte(t *testing.T) {
+	f := tempFile(t)
+	wf, err := Create(f, 8000, 1, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wf.Data.PCMWriter().Write([]byte{1, 2, 3}); err != nil {
+		t.Fatal(err)
+	}
+	wf.List = &ListChunk{
+		SubChunkID: LIST,
+		TypeID:     INFO,
+		SubChunks:  []InfoChunk{{ID: INAM, Text: []byte("odd")}},
+	}
+	size, err := wf.Encode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	end := bytes.Index(b, DATA[:]) + DataChunkHdrSize + 3
+	if size != int64(len(b)) || b[end] != 0 {
+		t.Fatalf("no pad byte after the 3 byte data chunk: % x", b)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	dec := gowav.NewDecoder(f)
+	dec.ReadMetadata()
+	if err := dec.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if dec.Metadata == nil || dec.Metadata.Title != "odd" {
+		t.Errorf("go-audio: LIST not decoded: %+v", dec.Metadata)
+	}
+
+	// files written without the pad byte decode as well
+	unpadded := append(b[:end:end], b[end+1:]...)
+	binary.LittleEndian.PutUint32(unpadded[4:8], uint32(len(unpadded)-8))
+	for _, b := range [][]byte{b, unpadded} {
+		got, err := Decode(bytes.NewReader(b))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.List == nil || got.List.InfoChunk(INAM) != "odd" {
+			t.Errorf("%d bytes: LIST not decoded: %+v", len(b), got.List)
+		}
+	}
+}
+
+func TestPackInfoChunk(t *testing.T) {
+	ic := InfoChunk{ID: INAM, Text: []byte("title")}
+	var a, b bytes.Buffer
+	if err := ic.Pack(&a); err != nil {
+		t.Fatal(err)
+	}
+	if err := ic.Pack(&b); err != nil {
+		t.Fatal(err)
+	}
+	if string(ic.Text) != "title" {
+		t.Errorf("Pack() modified Text: %q", ic.Text)
+	}
+	if !bytes.Equal(a.Bytes(), b.Bytes()) || a.Len() != ic.RawSize() {
+		t.Errorf("Pack() = %q, then %q", a.Bytes(), b.Bytes())
+	}
+}
+
+func TestInfoChunkPad(t *testing.T) {
+	l := ListChunk{
+		SubChunkID: LIST,
+		TypeID:     INFO,
+		SubChunks: []InfoChunk{
+			{ID: INAM, Text: []byte("ab")},
+			{ID: ICMT, Text: []byte("odd")},
+		},
+	}
+	var b bytes.Buffer
+	if err := l.Pack(&b); err != nil {
+		t.Fatal(err)
+	}
+	want := "LIST\x1c\x00\x00\x00INFO" +
+		"INAM\x03\x00\x00\x00ab\x00\x00" +
+		"ICMT\x04\x00\x00\x00odd\x00"
+	if b.String() != want || b.Len() != l.RawSize() {
+		t.Errorf("Pack() = %q, want %q", b.Bytes(), want)
+	}
+
+	var got ListChunk
+	if err := got.Unpack(&b); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.SubChunks) != 2 || got.InfoChunk(INAM) != "ab" || got.InfoChunk(ICMT) != "odd" {
+		t.Errorf("Unpack() = %+v", got.SubChunks)
+	}
+
+	// as written by go-audio, without pad bytes
+	nopad := "LIST\x1b\x00\x00\x00INFO" +
+		"INAM\x03\x00\x00\x00ab\x00" +
+		"ICMT\x04\x00\x00\x00odd\x00"
+	got = ListChunk{}
+	if err := got.Unpack(strings.NewReader(nopad)); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.SubChunks) != 2 || got.InfoChunk(INAM) != "ab" || got.InfoChunk(ICMT) != "odd" {
+		t.Errorf("Unpack() without pad = %+v", got.SubChunks)
+	}
+}
+
 func TestPackRIFF2(t *testing.T) {
 	want, err := ioutil.ReadFile("riffhdr.golden")
 	if err != nil {
@@ -233,6 +453,17 @@ func TestPackFmt(t *testing.T) {
 			got, len(got), b, len(b),
 		)
 	}
+
+	// the extension does not fit in the declared size
+	fmt.SubChunkSize, fmt.CbSize = 18, 22
+	if err := fmt.Pack(io.Discard); err == nil {
+		t.Error("Pack() of an extension larger than the chunk succeeded")
+	}
+	fmt.SubChunkSize = 40
+	buf.Reset()
+	if err := fmt.Pack(buf); err != nil || buf.Len() != 48 {
+		t.Errorf("Pack() = %d bytes, %v, want 48 bytes", buf.Len(), err)
+	}
 }
 
 func TestPackDataChunk(t *testing.T) {
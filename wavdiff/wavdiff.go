@@ -0,0 +1,244 @@
+// Package wavdiff compares two wav files, both their headers and their
+// samples.
+package wavdiff
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/cakturk/pkg/wav"
+)
+
+// Options controls Compare.
+type Options struct {
+	// Tolerance is the largest absolute sample difference, relative to
+	// full scale, for which two samples are considered equal.
+	Tolerance float64
+	// IgnoreMetadata skips comparing LIST/INFO and cue chunks.
+	IgnoreMetadata bool
+}
+
+// Report describes the differences between two wav files a and b. Errors
+// are measured with b as the reference.
+type Report struct {
+	Header    []string // header and metadata differences
+	Frames    [2]int64
+	FirstDiff int64 // first differing frame, -1 if none
+	MaxDiff   float64
+	RMSError  float64
+	SNR       float64 // dB
+	PSNR      float64 // dB, relative to full scale
+}
+
+// Equal reports whether the files have the same header and metadata and the
+// same number of frames, and no sample differs by more than the tolerance.
+func (r *Report) Equal() bool {
+	return len(r.Header) == 0 && r.Frames[0] == r.Frames[1] && r.FirstDiff < 0
+}
+
+func (r *Report) String() string {
+	var b strings.Builder
+	for _, h := range r.Header {
+		fmt.Fprintf(&b, "header: %s\n", h)
+	}
+	if r.Frames[0] != r.Frames[1] {
+		fmt.Fprintf(&b, "frames: %d != %d\n", r.Frames[0], r.Frames[1])
+	}
+	if r.FirstDiff >= 0 {
+		fmt.Fprintf(&b, "first differing frame: %d\n", r.FirstDiff)
+	}
+	fmt.Fprintf(&b, "max diff: %g, rms error: %g, snr: %.2f dB, psnr: %.2f dB",
+		r.MaxDiff, r.RMSError, r.SNR, r.PSNR)
+	return b.String()
+}
+
+// Compare decodes the wav files read from a and b and compares them.
+func Compare(a, b io.ReadSeeker, opts *Options) (*Report, error) {
+	wa, err := wav.Decode(a)
+	if err != nil {
+		return nil, fmt.Errorf("wavdiff: a: %w", err)
+	}
+	wb, err := wav.Decode(b)
+	if err != nil {
+		return nil, fmt.Errorf("wavdiff: b: %w", err)
+	}
+	return CompareWav(wa, wb, opts)
+}
+
+// CompareWav compares two decoded wav files.
+func CompareWav(a, b *wav.WavFile, opts *Options) (*Report, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+	r := &Report{
+		Header:    headerDiff(&a.Fmt, &b.Fmt),
+		FirstDiff: -1,
+	}
+	if !opts.IgnoreMetadata {
+		r.Header = append(r.Header, metadataDiff(a, b)...)
+	}
+	for i, wf := range []*wav.WavFile{a, b} {
+		if wf.Fmt.BlockAlign == 0 {
+			return nil, fmt.Errorf("wavdiff: %c: zero block align", 'a'+i)
+		}
+		r.Frames[i] = wf.NumFrames()
+	}
+	if a.Fmt.NumChans != b.Fmt.NumChans {
+		// samples can't be lined up
+		r.MaxDiff, r.RMSError, r.SNR, r.PSNR = math.NaN(), math.NaN(), math.NaN(), math.NaN()
+		return r, nil
+	}
+	if err := r.compareSamples(a, b, opts.Tolerance); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *Report) compareSamples(a, b *wav.WavFile, tolerance float64) error {
+	var frs [2]*wav.FrameReader
+	for i, wf := range []*wav.WavFile{a, b} {
+		pcm := wf.Data.PCMReader()
+		if pcm == nil {
+			return fmt.Errorf("wavdiff: %c: nil PCM reader", 'a'+i)
+		}
+		if _, err := pcm.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		fr, err := wf.FrameReader()
+		if err != nil {
+			return err
+		}
+		frs[i] = fr
+	}
+	nchans := int(a.Fmt.NumChans)
+	pa := make([]float64, 4096*nchans)
+	pb := make([]float64, 4096*nchans)
+	var (
+		pos          int64
+		noise, power float64
+		count        int64
+	)
+	for {
+		na, erra := wav.ReadFullFrames(frs[0], pa, nchans)
+		nb, errb := wav.ReadFullFrames(frs[1], pb, nchans)
+		if erra != nil && erra != io.EOF {
+			return erra
+		}
+		if errb != nil && errb != io.EOF {
+			return errb
+		}
+		n := min(na, nb)
+		for i, sa := range pa[:n*nchans] {
+			sb := pb[i]
+			d := math.Abs(sa - sb)
+			if d > tolerance && r.FirstDiff < 0 {
+				r.FirstDiff = pos + int64(i/nchans)
+			}
+			r.MaxDiff = max(r.MaxDiff, d)
+			noise += d * d
+			power += sb * sb
+		}
+		count += int64(n * nchans)
+		pos += int64(n)
+		if na != nb || erra == io.EOF || errb == io.EOF {
+			break
+		}
+	}
+	if r.FirstDiff < 0 && r.Frames[0] != r.Frames[1] {
+		r.FirstDiff = min(r.Frames[0], r.Frames[1])
+	}
+	if count > 0 {
+		r.RMSError = math.Sqrt(noise / float64(count))
+		noise /= float64(count)
+		power /= float64(count)
+	}
+	r.SNR = 10 * math.Log10(power/noise)
+	r.PSNR = 10 * math.Log10(1/noise)
+	if noise == 0 {
+		r.SNR, r.PSNR = math.Inf(1), math.Inf(1)
+	}
+	return nil
+}
+
+func headerDiff(a, b *wav.FmtChunk) []string {
+	var diffs []string
+	check := func(name string, x, y any) {
+		if x != y {
+			diffs = append(diffs, fmt.Sprintf("%s: %v != %v", name, x, y))
+		}
+	}
+	check("AudioFormat", a.AudioFormat, b.AudioFormat)
+	check("NumChans", a.NumChans, b.NumChans)
+	check("SampleRate", a.SampleRate, b.SampleRate)
+	check("ByteRate", a.ByteRate, b.ByteRate)
+	check("BlockAlign", a.BlockAlign, b.BlockAlign)
+	check("BitsPerSample", a.BitsPerSample, b.BitsPerSample)
+	check("ValidBitsPerSample", a.ValidBitsPerSample, b.ValidBitsPerSample)
+	check("ChannelMask", a.ChannelMask, b.ChannelMask)
+	check("SubFormat", a.SubFormat, b.SubFormat)
+	return diffs
+}
+
+func metadataDiff(a, b *wav.WavFile) []string {
+	var diffs []string
+	info := func(wf *wav.WavFile) map[[4]byte]string {
+		m := make(map[[4]byte]string)
+		if wf.List != nil {
+			for _, ic := range wf.List.SubChunks {
+				m[ic.ID] = string(ic.Text)
+			}
+		}
+		return m
+	}
+	ia, ib := info(a), info(b)
+	for id, ta := range ia {
+		if tb, ok := ib[id]; !ok || ta != tb {
+			diffs = append(diffs, fmt.Sprintf("%s: %q != %q", id[:], ta, tb))
+		}
+	}
+	for id, tb := range ib {
+		if _, ok := ia[id]; !ok {
+			diffs = append(diffs, fmt.Sprintf("%s: %q != %q", id[:], "", tb))
+		}
+	}
+	sort.Strings(diffs)
+	var ca, cb []uint32
+	if a.Cue != nil {
+		for _, p := range a.Cue.Points {
+			ca = append(ca, p.SampleOffset)
+		}
+	}
+	if b.Cue != nil {
+		for _, p := range b.Cue.Points {
+			cb = append(cb, p.SampleOffset)
+		}
+	}
+	if fmt.Sprint(ca) != fmt.Sprint(cb) {
+		diffs = append(diffs, fmt.Sprintf("cue: %v != %v", ca, cb))
+	}
+	return diffs
+}
+
+// TB is the subset of testing.TB used by AssertEqual.
+type TB interface {
+	Helper()
+	Errorf(format string, args ...any)
+}
+
+// AssertEqual compares the wav files got and want and reports an error on tb
+// if they differ.
+func AssertEqual(tb TB, got, want io.ReadSeeker, opts *Options) *Report {
+	tb.Helper()
+	r, err := Compare(got, want, opts)
+	if err != nil {
+		tb.Errorf("wavdiff: %v", err)
+		return nil
+	}
+	if !r.Equal() {
+		tb.Errorf("wav files differ:\n%s", r)
+	}
+	return r
+}
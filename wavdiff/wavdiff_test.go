@@ -0,0 +1,147 @@
+package wavdiff
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/cakturk/pkg/wav"
+	"github.com/cakturk/pkg/wavgen"
+)
+
+// makeWav returns an 8 kHz file holding the interleaved samples.
+func makeWav(t *testing.T, nchans, nbits int, samples []float64) *os.File {
+	t.Helper()
+	f, err := os.CreateTemp(t.TempDir(), "*.wav")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+	sigs := make([]wavgen.Signal, nchans)
+	for c := range sigs {
+		sigs[c] = wavgen.SignalFunc(func(n int64, _ int) float64 {
+			return samples[int(n)*nchans+c]
+		})
+	}
+	format := wavgen.Format{SampleRate: 8000, Channels: nchans, Bits: nbits}
+	if err := wavgen.Write(f, format, int64(len(samples)/nchans), sigs...); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func TestCompareEqual(t *testing.T) {
+	samples := []float64{0, 0.5, -0.5, 0.25}
+	a := makeWav(t, 2, 16, samples)
+	b := makeWav(t, 2, 16, samples)
+	r := AssertEqual(t, a, b, nil)
+	if r.MaxDiff != 0 || !math.IsInf(r.SNR, 1) || r.Frames != [2]int64{2, 2} {
+		t.Errorf("unexpected report: %+v", r)
+	}
+}
+
+func TestCompareErrors(t *testing.T) {
+	a := makeWav(t, 1, 16, []float64{0, 0.5})
+	// without io.ReaderAt the files have no PCM reader
+	rs := struct{ io.ReadSeeker }{a}
+	if _, err := Compare(rs, rs, nil); err == nil {
+		t.Error("Compare() without a PCM reader succeeded")
+	}
+
+	a.Seek(0, io.SeekStart)
+	wa, err := wav.Decode(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wb := *wa
+	wb.Fmt.BlockAlign = 0
+	if _, err := CompareWav(wa, &wb, nil); err == nil {
+		t.Error("CompareWav() with a zero block align succeeded")
+	}
+}
+
+func TestCompareSamples(t *testing.T) {
+	a := makeWav(t, 1, 16, []float64{0.5, 0.5, 0.5, 0.5})
+	b := makeWav(t, 1, 16, []float64{0.5, 0.5, 0.5 + 1.0/1024, 0.5})
+	r, err := Compare(a, b, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Equal() || r.FirstDiff != 2 || r.MaxDiff != 1.0/1024 {
+		t.Errorf("unexpected report: %+v", r)
+	}
+	if want := 1.0 / 2048; r.RMSError != want {
+		t.Errorf("RMSError = %v, want %v", r.RMSError, want)
+	}
+	if r.SNR < 54 || r.PSNR < r.SNR {
+		t.Errorf("SNR = %.2f, PSNR = %.2f", r.SNR, r.PSNR)
+	}
+
+	a.Seek(0, io.SeekStart)
+	b.Seek(0, io.SeekStart)
+	r, err = Compare(a, b, &Options{Tolerance: 1.0 / 512})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.Equal() {
+		t.Errorf("difference within tolerance reported: %s", r)
+	}
+}
+
+func TestCompareHeader(t *testing.T) {
+	wa, err := wav.Decode(makeWav(t, 1, 16, []float64{0.5, 0.25, 0}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	wa.List = &wav.ListChunk{
+		SubChunkID: wav.LIST,
+		TypeID:     wav.INFO,
+		SubChunks:  []wav.InfoChunk{{ID: wav.INAM, Text: []byte("a")}},
+	}
+	wb, err := wav.Decode(makeWav(t, 1, 24, []float64{0.5, 0.25}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := CompareWav(wa, wb, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"ByteRate: 16000 != 24000",
+		"BlockAlign: 2 != 3",
+		"BitsPerSample: 16 != 24",
+		`INAM: "a" != ""`,
+	}
+	if fmt.Sprint(r.Header) != fmt.Sprint(want) {
+		t.Errorf("Header = %q, want %q", r.Header, want)
+	}
+	if r.FirstDiff != 2 || r.MaxDiff != 0 {
+		t.Errorf("unexpected report: %+v", r)
+	}
+	if s := r.String(); !strings.Contains(s, "frames: 3 != 2") {
+		t.Errorf("String() = %q", s)
+	}
+}
+
+type recorder struct{ errs []string }
+
+func (r *recorder) Helper() {}
+func (r *recorder) Errorf(format string, args ...any) {
+	r.errs = append(r.errs, fmt.Sprintf(format, args...))
+}
+
+func TestAssertEqual(t *testing.T) {
+	a := makeWav(t, 1, 8, []float64{0.5})
+	b := makeWav(t, 1, 8, []float64{-0.5})
+	var rec recorder
+	AssertEqual(&rec, a, b, nil)
+	if len(rec.errs) != 1 || !strings.Contains(rec.errs[0], "first differing frame: 0") {
+		t.Errorf("got %q", rec.errs)
+	}
+}
@@ -0,0 +1,274 @@
+// Package waveform computes min/max peak overviews of wav files suitable for
+// drawing waveforms. Overviews can be stored in the binary .dat format of
+// audiowaveform or as JSON.
+package waveform
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/cakturk/pkg/wav"
+)
+
+// Waveform holds min/max pairs for every SamplesPerPixel frames. Data is
+// interleaved: for each pixel, a min and a max for each channel. Version is
+// the version of the .dat format it is written as: 2, or 1 for mono
+// overviews read by older tools.
+type Waveform struct {
+	Version         int     `json:"version"`
+	Channels        int     `json:"channels"`
+	SampleRate      int     `json:"sample_rate"`
+	SamplesPerPixel int     `json:"samples_per_pixel"`
+	Bits            int     `json:"bits"`
+	Length          int     `json:"length"` // pixels
+	Data            []int16 `json:"data"`
+}
+
+// MinMax returns the min and max values of the given channel at pixel i.
+func (w *Waveform) MinMax(i, channel int) (lo, hi int16) {
+	j := 2 * (i*w.Channels + channel)
+	return w.Data[j], w.Data[j+1]
+}
+
+// Generate reads wf once and returns one overview for each of the given zoom
+// levels, in samples per pixel. bits is the resolution of the values, 8 or
+// 16.
+func Generate(wf *wav.WavFile, bits int, zoom ...int) ([]*Waveform, error) {
+	if bits != 8 && bits != 16 {
+		return nil, fmt.Errorf("waveform: unsupported resolution: %d bits", bits)
+	}
+	if len(zoom) == 0 {
+		return nil, errors.New("waveform: no zoom levels")
+	}
+	nchans := int(wf.Fmt.NumChans)
+	levels := make([]*level, len(zoom))
+	for i, z := range zoom {
+		if z <= 0 {
+			return nil, fmt.Errorf("waveform: invalid zoom level: %d", z)
+		}
+		levels[i] = newLevel(z, nchans, bits, int(wf.Fmt.SampleRate))
+	}
+	pcm := wf.Data.PCMReader()
+	if pcm == nil {
+		return nil, errors.New("waveform: nil PCM reader")
+	}
+	if _, err := pcm.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	fr, err := wf.FrameReader()
+	if err != nil {
+		return nil, err
+	}
+	p := make([]float64, 4096*nchans)
+	for {
+		n, err := fr.ReadFrames(p)
+		for _, l := range levels {
+			l.add(p[:n*nchans])
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	out := make([]*Waveform, len(levels))
+	for i, l := range levels {
+		out[i] = l.finish()
+	}
+	return out, nil
+}
+
+// level accumulates a single zoom level.
+type level struct {
+	w      *Waveform
+	scale  float64
+	count  int // frames in the current pixel
+	lo, hi []int16
+}
+
+func newLevel(spp, nchans, bits, sampleRate int) *level {
+	l := &level{
+		w: &Waveform{
+			Version:         2,
+			Channels:        nchans,
+			SampleRate:      sampleRate,
+			SamplesPerPixel: spp,
+			Bits:            bits,
+		},
+		scale: float64(int(1) << (bits - 1)),
+		lo:    make([]int16, nchans),
+		hi:    make([]int16, nchans),
+	}
+	l.reset()
+	return l
+}
+
+func (l *level) reset() {
+	l.count = 0
+	for c := range l.lo {
+		l.lo[c], l.hi[c] = math.MaxInt16, math.MinInt16
+	}
+}
+
+func (l *level) add(p []float64) {
+	nchans := len(l.lo)
+	for i := 0; i+nchans <= len(p); i += nchans {
+		for c, s := range p[i : i+nchans] {
+			v := l.quantize(s)
+			l.lo[c] = min(l.lo[c], v)
+			l.hi[c] = max(l.hi[c], v)
+		}
+		if l.count++; l.count == l.w.SamplesPerPixel {
+			l.flush()
+		}
+	}
+}
+
+func (l *level) quantize(s float64) int16 {
+	v := math.Floor(s * l.scale)
+	return int16(max(-l.scale, min(l.scale-1, v)))
+}
+
+func (l *level) flush() {
+	for c := range l.lo {
+		l.w.Data = append(l.w.Data, l.lo[c], l.hi[c])
+	}
+	l.w.Length++
+	l.reset()
+}
+
+func (l *level) finish() *Waveform {
+	if l.count > 0 {
+		l.flush()
+	}
+	return l.w
+}
+
+// Zoom returns a coarser overview of w whose pixels cover factor pixels of w.
+func (w *Waveform) Zoom(factor int) *Waveform {
+	z := *w
+	z.SamplesPerPixel *= factor
+	z.Length = (w.Length + factor - 1) / factor
+	z.Data = make([]int16, 0, 2*z.Length*w.Channels)
+	for i := 0; i < w.Length; i += factor {
+		for c := 0; c < w.Channels; c++ {
+			lo, hi := w.MinMax(i, c)
+			for j := i + 1; j < min(i+factor, w.Length); j++ {
+				l, h := w.MinMax(j, c)
+				lo, hi = min(lo, l), max(hi, h)
+			}
+			z.Data = append(z.Data, lo, hi)
+		}
+	}
+	return &z
+}
+
+const (
+	datFlag8Bit = 1 << 0
+)
+
+// WriteTo writes w in the .dat format of audiowaveform, as version
+// w.Version.
+func (w *Waveform) WriteTo(wr io.Writer) (int64, error) {
+	var hdr []byte
+	le := binary.LittleEndian
+	version := w.Version
+	switch {
+	case version == 1 && w.Channels != 1:
+		return 0, fmt.Errorf("waveform: version 1 cannot hold %d channels", w.Channels)
+	case version != 1 && version != 2:
+		return 0, fmt.Errorf("waveform: unsupported version: %d", version)
+	}
+	var flags uint32
+	if w.Bits == 8 {
+		flags |= datFlag8Bit
+	}
+	hdr = le.AppendUint32(hdr, uint32(version))
+	hdr = le.AppendUint32(hdr, flags)
+	hdr = le.AppendUint32(hdr, uint32(w.SampleRate))
+	hdr = le.AppendUint32(hdr, uint32(w.SamplesPerPixel))
+	hdr = le.AppendUint32(hdr, uint32(w.Length))
+	if version == 2 {
+		hdr = le.AppendUint32(hdr, uint32(w.Channels))
+	}
+	data := hdr
+	for _, v := range w.Data {
+		if w.Bits == 8 {
+			data = append(data, byte(int8(v)))
+		} else {
+			data = le.AppendUint16(data, uint16(v))
+		}
+	}
+	n, err := wr.Write(data)
+	return int64(n), err
+}
+
+// ReadDat reads an overview in the .dat format of audiowaveform.
+func ReadDat(r io.Reader) (*Waveform, error) {
+	le := binary.LittleEndian
+	hdr := make([]byte, 20)
+	if _, err := io.ReadFull(r, hdr); err != nil {
+		return nil, err
+	}
+	w := &Waveform{
+		Version:         int(le.Uint32(hdr[0:])),
+		Channels:        1,
+		SampleRate:      int(le.Uint32(hdr[8:])),
+		SamplesPerPixel: int(le.Uint32(hdr[12:])),
+		Bits:            16,
+		Length:          int(le.Uint32(hdr[16:])),
+	}
+	if le.Uint32(hdr[4:])&datFlag8Bit != 0 {
+		w.Bits = 8
+	}
+	switch w.Version {
+	case 1:
+	case 2:
+		if _, err := io.ReadFull(r, hdr[:4]); err != nil {
+			return nil, err
+		}
+		w.Channels = int(le.Uint32(hdr))
+	default:
+		return nil, fmt.Errorf("waveform: unsupported version: %d", w.Version)
+	}
+	if w.Channels < 1 || w.Channels > math.MaxUint16 {
+		return nil, fmt.Errorf("waveform: invalid number of channels: %d", w.Channels)
+	}
+	// The header is not trusted to size the buffer; the data grows with
+	// what is actually read.
+	size := int64(w.Length) * int64(w.Channels) * 2 * int64(w.Bits/8)
+	p, err := io.ReadAll(io.LimitReader(r, size))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(p)) != size {
+		return nil, io.ErrUnexpectedEOF
+	}
+	w.Data = make([]int16, len(p)/(w.Bits/8))
+	for i := range w.Data {
+		if w.Bits == 8 {
+			w.Data[i] = int16(int8(p[i]))
+		} else {
+			w.Data[i] = int16(le.Uint16(p[2*i:]))
+		}
+	}
+	return w, nil
+}
+
+// Levels returns zoom levels starting at base samples per pixel, doubling
+// until a single pixel covers at least frames samples.
+func Levels(base, frames int) []int {
+	if base <= 0 {
+		return nil
+	}
+	zoom := []int{base}
+	for z := base; z < frames; {
+		z *= 2
+		zoom = append(zoom, z)
+	}
+	return zoom
+}
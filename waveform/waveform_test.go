@@ -0,0 +1,174 @@
+package waveform
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/cakturk/pkg/wav"
+	"github.com/cakturk/pkg/wavgen"
+)
+
+// makeWav writes an 8 kHz 16-bit wav file holding the interleaved samples.
+func makeWav(t *testing.T, nchans int, samples []float64) *wav.WavFile {
+	t.Helper()
+	f, err := os.Create(filepath.Join(t.TempDir(), "in.wav"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+	sigs := make([]wavgen.Signal, nchans)
+	for c := range sigs {
+		sigs[c] = wavgen.SignalFunc(func(n int64, _ int) float64 {
+			return samples[int(n)*nchans+c]
+		})
+	}
+	format := wavgen.Format{SampleRate: 8000, Channels: nchans, Bits: 16}
+	if err := wavgen.Write(f, format, int64(len(samples)/nchans), sigs...); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	wf, err := wav.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return wf
+}
+
+func TestGenerate(t *testing.T) {
+	samples := []float64{
+		0.5, -0.5,
+		-0.25, 0.25,
+		0, 1,
+		0.125, -1,
+		-0.5, 0,
+	}
+	wf := makeWav(t, 2, samples)
+	got, err := Generate(wf, 16, 2, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("got %d levels, want 2", len(got))
+	}
+	want := []int16{
+		-8192, 16384, -16384, 8192,
+		0, 4096, -32768, 32767,
+		-16384, -16384, 0, 0,
+	}
+	if w := got[0]; w.Length != 3 || w.Channels != 2 || !reflect.DeepEqual(w.Data, want) {
+		t.Errorf("got %+v, want data %v", w, want)
+	}
+	if z := got[0].Zoom(2); !reflect.DeepEqual(z.Data, got[1].Data) || z.SamplesPerPixel != 4 {
+		t.Errorf("Zoom(2) = %+v, want %+v", z, got[1])
+	}
+
+	got8, err := Generate(wf, 8, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want8 := []int16{-64, 64, -128, 127}
+	if !reflect.DeepEqual(got8[0].Data, want8) {
+		t.Errorf("got %v, want %v", got8[0].Data, want8)
+	}
+}
+
+func TestDatRoundTrip(t *testing.T) {
+	for _, nchans := range []int{1, 2} {
+		for _, bits := range []int{8, 16} {
+			samples := make([]float64, 100*nchans)
+			for i := range samples {
+				samples[i] = float64(i%17)/8 - 1
+			}
+			levels, err := Generate(makeWav(t, nchans, samples), bits, 7)
+			if err != nil {
+				t.Fatal(err)
+			}
+			w := levels[0]
+			var buf bytes.Buffer
+			n, err := w.WriteTo(&buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := 24 + len(w.Data)*bits/8; n != int64(want) {
+				t.Errorf("wrote %d bytes, want %d", n, want)
+			}
+			got, err := ReadDat(&buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, w) {
+				t.Errorf("%dch/%d-bit: got %+v, want %+v", nchans, bits, got, w)
+			}
+
+			w.Version = 1
+			buf.Reset()
+			_, err = w.WriteTo(&buf)
+			if nchans > 1 {
+				if err == nil {
+					t.Errorf("%dch: version 1 written", nchans)
+				}
+				continue
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, err := ReadDat(&buf); err != nil || !reflect.DeepEqual(got, w) {
+				t.Errorf("version 1: got %+v, %v, want %+v", got, err, w)
+			}
+		}
+	}
+}
+
+func TestReadDatErrors(t *testing.T) {
+	le := binary.LittleEndian
+	var hdr []byte
+	for _, v := range []uint32{2, 0, 8000, 256, math.MaxUint32, 2} {
+		hdr = le.AppendUint32(hdr, v)
+	}
+	// the length claims 16 GiB of data which isn't there
+	if _, err := ReadDat(bytes.NewReader(append(hdr, 1, 2, 3, 4))); err != io.ErrUnexpectedEOF {
+		t.Errorf("got %v, want io.ErrUnexpectedEOF", err)
+	}
+	le.PutUint32(hdr[20:], 0)
+	if _, err := ReadDat(bytes.NewReader(hdr)); err == nil {
+		t.Error("zero channels accepted")
+	}
+}
+
+func TestGenerateNoReaderAt(t *testing.T) {
+	wf := makeWav(t, 1, []float64{0.5, -0.5})
+	wf.Data = wav.DataChunk{SubChunkID: wf.Data.SubChunkID, SubChunkSize: wf.Data.SubChunkSize}
+	if _, err := Generate(wf, 16, 2); err == nil {
+		t.Error("got no error")
+	}
+}
+
+func TestJSON(t *testing.T) {
+	levels, err := Generate(makeWav(t, 1, []float64{0.5, -0.5, 0.25}), 8, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(levels[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"version":2,"channels":1,"sample_rate":8000,"samples_per_pixel":2,"bits":8,"length":2,"data":[-64,64,32,32]}`
+	if string(b) != want {
+		t.Errorf("got %s, want %s", b, want)
+	}
+}
+
+func TestLevels(t *testing.T) {
+	if got, want := Levels(256, 1000), []int{256, 512, 1024}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
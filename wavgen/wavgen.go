@@ -0,0 +1,220 @@
+// Package wavgen generates test signals and writes them as wav files. The
+// output only depends on the parameters, noise included, so tests can assert
+// the exact bytes generated.
+package wavgen
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand/v2"
+	"time"
+
+	"github.com/cakturk/pkg/wav"
+)
+
+// Signal is a mono signal. Sample is called with n = 0, 1, 2, ... in order
+// and returns a value in [-1, 1].
+type Signal interface {
+	Sample(n int64, sampleRate int) float64
+}
+
+// SignalFunc adapts a stateless function to Signal.
+type SignalFunc func(n int64, sampleRate int) float64
+
+func (f SignalFunc) Sample(n int64, sampleRate int) float64 {
+	return f(n, sampleRate)
+}
+
+// Silence is digital silence.
+var Silence Signal = SignalFunc(func(int64, int) float64 { return 0 })
+
+// Sine returns a sine tone of the given frequency and amplitude.
+func Sine(freq, amp float64) Signal {
+	return SignalFunc(func(n int64, rate int) float64 {
+		return amp * math.Sin(2*math.Pi*freq*float64(n)/float64(rate))
+	})
+}
+
+// Multitone returns the sum of sine tones of the given frequencies, each
+// with the given amplitude.
+func Multitone(amp float64, freqs ...float64) Signal {
+	return SignalFunc(func(n int64, rate int) float64 {
+		var s float64
+		for _, f := range freqs {
+			s += amp * math.Sin(2*math.Pi*f*float64(n)/float64(rate))
+		}
+		return s
+	})
+}
+
+// Sweep returns a sine sweep from f0 to f1 Hz over d. The frequency changes
+// exponentially if log is set, linearly otherwise. The sweep keeps going
+// past d.
+func Sweep(f0, f1 float64, d time.Duration, amp float64, log bool) Signal {
+	T := d.Seconds()
+	return SignalFunc(func(n int64, rate int) float64 {
+		t := float64(n) / float64(rate)
+		var phase float64
+		if log {
+			k := math.Log(f1 / f0)
+			phase = f0 * T / k * (math.Exp(t/T*k) - 1)
+		} else {
+			phase = f0*t + (f1-f0)*t*t/(2*T)
+		}
+		return amp * math.Sin(2*math.Pi*phase)
+	})
+}
+
+// Impulse returns a signal of a single sample of amplitude amp at frame
+// at, repeated every period frames if period is positive.
+func Impulse(amp float64, at, period int64) Signal {
+	return SignalFunc(func(n int64, _ int) float64 {
+		if n == at || period > 0 && n > at && (n-at)%period == 0 {
+			return amp
+		}
+		return 0
+	})
+}
+
+type whiteNoise struct {
+	amp float64
+	rnd *rand.Rand
+}
+
+// WhiteNoise returns uniformly distributed white noise in [-amp, amp)
+// generated from seed.
+func WhiteNoise(amp float64, seed uint64) Signal {
+	return &whiteNoise{amp: amp, rnd: newRand(seed)}
+}
+
+func (w *whiteNoise) Sample(int64, int) float64 {
+	return w.amp * uniform(w.rnd)
+}
+
+type pinkNoise struct {
+	amp float64
+	rnd *rand.Rand
+	b   [7]float64
+}
+
+// PinkNoise returns noise with a -3 dB/octave spectrum generated from seed.
+// It filters white noise using Paul Kellet's refined method and is scaled to
+// stay within [-amp, amp] in practice.
+func PinkNoise(amp float64, seed uint64) Signal {
+	return &pinkNoise{amp: amp, rnd: newRand(seed)}
+}
+
+func (p *pinkNoise) Sample(int64, int) float64 {
+	w := uniform(p.rnd)
+	b := &p.b
+	b[0] = 0.99886*b[0] + w*0.0555179
+	b[1] = 0.99332*b[1] + w*0.0750759
+	b[2] = 0.96900*b[2] + w*0.1538520
+	b[3] = 0.86650*b[3] + w*0.3104856
+	b[4] = 0.55000*b[4] + w*0.5329522
+	b[5] = -0.7616*b[5] - w*0.0168980
+	s := b[0] + b[1] + b[2] + b[3] + b[4] + b[5] + b[6] + w*0.5362
+	b[6] = w * 0.115926
+	return max(-p.amp, min(p.amp, p.amp*s/5))
+}
+
+func newRand(seed uint64) *rand.Rand {
+	return rand.New(rand.NewPCG(seed, seed^0x9e3779b97f4a7c15))
+}
+
+// uniform returns a value in [-1, 1) using the top 53 bits of the generator
+// so that the result does not depend on how math/rand derives floats.
+func uniform(r *rand.Rand) float64 {
+	return float64(r.Uint64()>>11)/(1<<52) - 1
+}
+
+// Format describes the wav file to generate.
+type Format struct {
+	SampleRate int
+	Channels   int
+	Bits       int
+	Float      bool // IEEE float samples, Bits must be 32 or 64
+}
+
+func (f Format) fmtChunk() (wav.FmtChunk, error) {
+	if f.SampleRate <= 0 || f.Channels <= 0 {
+		return wav.FmtChunk{}, errors.New("wavgen: invalid sample rate or channel count")
+	}
+	switch {
+	case f.Float && (f.Bits == 32 || f.Bits == 64):
+	case !f.Float && f.Bits%8 == 0 && f.Bits >= 8 && f.Bits <= 32:
+	default:
+		return wav.FmtChunk{}, fmt.Errorf("wavgen: unsupported bit depth: %d", f.Bits)
+	}
+	blockAlign := uint16(f.Channels * f.Bits / 8)
+	format := uint16(wav.FormatPCM)
+	if f.Float {
+		format = wav.FormatIEEEFloat
+	}
+	return wav.FmtChunk{
+		SubChunkID:    wav.FMT,
+		SubChunkSize:  0x10,
+		AudioFormat:   format,
+		NumChans:      uint16(f.Channels),
+		SampleRate:    uint32(f.SampleRate),
+		ByteRate:      uint32(f.SampleRate) * uint32(blockAlign),
+		BlockAlign:    blockAlign,
+		BitsPerSample: uint16(f.Bits),
+	}, nil
+}
+
+// Write generates frames frames and writes them as a wav file into w. With a
+// single signal every channel carries the same signal, otherwise there must
+// be one signal for each channel.
+func Write(w io.WriteSeeker, f Format, frames int64, sigs ...Signal) error {
+	fc, err := f.fmtChunk()
+	if err != nil {
+		return err
+	}
+	if len(sigs) != 1 && len(sigs) != f.Channels {
+		return fmt.Errorf("wavgen: %d signals for %d channels", len(sigs), f.Channels)
+	}
+	var wf *wav.WavFile
+	if f.Float {
+		wf, err = wav.CreateFmt(w, fc)
+	} else {
+		wf, err = wav.Create(w, f.SampleRate, f.Channels, f.Bits)
+	}
+	if err != nil {
+		return err
+	}
+	fw, err := wf.FrameWriter()
+	if err != nil {
+		return err
+	}
+	const chunk = 4096
+	p := make([]float64, chunk*f.Channels)
+	for n := int64(0); n < frames; {
+		m := min(chunk, frames-n)
+		for i := int64(0); i < m; i++ {
+			if len(sigs) == 1 {
+				s := sigs[0].Sample(n+i, f.SampleRate)
+				for c := 0; c < f.Channels; c++ {
+					p[int(i)*f.Channels+c] = s
+				}
+				continue
+			}
+			for c, sig := range sigs {
+				p[int(i)*f.Channels+c] = sig.Sample(n+i, f.SampleRate)
+			}
+		}
+		if _, err := fw.WriteFrames(p[:m*int64(f.Channels)]); err != nil {
+			return err
+		}
+		n += m
+	}
+	_, err = wf.Encode(w)
+	return err
+}
+
+// Frames returns the number of frames in d at the given sample rate.
+func Frames(d time.Duration, sampleRate int) int64 {
+	return int64(math.Round(d.Seconds() * float64(sampleRate)))
+}
@@ -0,0 +1,145 @@
+package wavgen
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cakturk/pkg/wav"
+)
+
+func generate(t *testing.T, f Format, frames int64, sigs ...Signal) []byte {
+	t.Helper()
+	name := filepath.Join(t.TempDir(), "gen.wav")
+	w, err := os.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	if err := Write(w, f, frames, sigs...); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func samples(t *testing.T, b []byte) (*wav.WavFile, []float64) {
+	t.Helper()
+	wf, err := wav.Decode(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fr, err := wf.FrameReader()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := make([]float64, wf.Data.SubChunkSize)
+	n, err := fr.ReadFrames(p)
+	if err != nil && err != io.EOF {
+		t.Fatal(err)
+	}
+	return wf, p[:n*int(wf.Fmt.NumChans)]
+}
+
+func TestImpulseBytes(t *testing.T) {
+	got := generate(t, Format{SampleRate: 8000, Channels: 1, Bits: 16}, 4, Impulse(0.5, 1, 2))
+	want := []byte{
+		'R', 'I', 'F', 'F', 44, 0, 0, 0, 'W', 'A', 'V', 'E',
+		'f', 'm', 't', ' ', 16, 0, 0, 0, 1, 0, 1, 0,
+		0x40, 0x1f, 0, 0, 0x80, 0x3e, 0, 0, 2, 0, 16, 0,
+		'd', 'a', 't', 'a', 8, 0, 0, 0,
+		0, 0, 0, 0x40, 0, 0, 0, 0x40,
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("got:  %#02v\nwant: %#02v", got, want)
+	}
+}
+
+func TestFormats(t *testing.T) {
+	formats := []Format{
+		{SampleRate: 44100, Channels: 1, Bits: 8},
+		{SampleRate: 48000, Channels: 2, Bits: 16},
+		{SampleRate: 96000, Channels: 6, Bits: 24},
+		{SampleRate: 22050, Channels: 3, Bits: 32},
+		{SampleRate: 16000, Channels: 2, Bits: 32, Float: true},
+		{SampleRate: 16000, Channels: 1, Bits: 64, Float: true},
+	}
+	for _, f := range formats {
+		wf, got := samples(t, generate(t, f, 100, Sine(1000, 0.5)))
+		if int(wf.Fmt.NumChans) != f.Channels || int(wf.Fmt.BitsPerSample) != f.Bits ||
+			int(wf.Fmt.SampleRate) != f.SampleRate {
+			t.Errorf("%+v: unexpected header %+v", f, wf.Fmt)
+		}
+		if len(got) != 100*f.Channels {
+			t.Fatalf("%+v: got %d samples", f, len(got))
+		}
+		tolerance := 1.0 / 128
+		for i, s := range got {
+			n := i / f.Channels
+			want := 0.5 * math.Sin(2*math.Pi*1000*float64(n)/float64(f.SampleRate))
+			if math.Abs(s-want) > tolerance {
+				t.Fatalf("%+v: sample %d = %v, want %v", f, i, s, want)
+			}
+		}
+	}
+	if err := Write(nil, Format{SampleRate: 8000, Channels: 1, Bits: 12}, 1, Silence); err == nil {
+		t.Error("expected an error for 12-bit samples")
+	}
+}
+
+func TestNoiseDeterministic(t *testing.T) {
+	f := Format{SampleRate: 8000, Channels: 2, Bits: 16}
+	a := generate(t, f, 1000, WhiteNoise(1, 42), PinkNoise(0.5, 42))
+	b := generate(t, f, 1000, WhiteNoise(1, 42), PinkNoise(0.5, 42))
+	c := generate(t, f, 1000, WhiteNoise(1, 43), PinkNoise(0.5, 43))
+	if !bytes.Equal(a, b) {
+		t.Error("same seeds produced different output")
+	}
+	if bytes.Equal(a, c) {
+		t.Error("different seeds produced the same output")
+	}
+	_, got := samples(t, a)
+	var mean float64
+	for i, s := range got {
+		if i%2 == 1 {
+			if math.Abs(s) > 0.5 {
+				t.Fatalf("pink noise sample %d out of range: %v", i, s)
+			}
+			continue
+		}
+		mean += s
+	}
+	if mean /= float64(len(got) / 2); math.Abs(mean) > 0.05 {
+		t.Errorf("white noise mean = %v", mean)
+	}
+}
+
+func TestSweep(t *testing.T) {
+	// count zero crossings in the first and last 100ms of a 1s sweep
+	crossings := func(p []float64) int {
+		n := 0
+		for i := 1; i < len(p); i++ {
+			if (p[i-1] < 0) != (p[i] < 0) {
+				n++
+			}
+		}
+		return n
+	}
+	for _, log := range []bool{false, true} {
+		f := Format{SampleRate: 48000, Channels: 1, Bits: 32, Float: true}
+		frames := Frames(time.Second, 48000)
+		_, got := samples(t, generate(t, f, frames, Sweep(100, 1000, time.Second, 1, log)))
+		head, tail := crossings(got[:4800]), crossings(got[len(got)-4800:])
+		// about 2 crossings per period
+		if head > 30 || tail < 160 {
+			t.Errorf("log %v: %d crossings at the start, %d at the end", log, head, tail)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package wavrec
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/cakturk/pkg/wav"
+)
+
+// ErrCapturing is returned by Trigger while a capture is in progress.
+var ErrCapturing = errors.New("wavrec: capture in progress")
+
+// Capture keeps the most recent audio written to it in a ring buffer. When
+// triggered, it writes the buffered audio followed by the audio written
+// after the trigger into a wav file. Capture is an io.Writer, so any source
+// of interleaved PCM can be copied into it with io.Copy. Write and Trigger
+// may be called from different goroutines.
+type Capture struct {
+	rate      int
+	nchans    int
+	nbits     int
+	blockSize int
+	post      int64 // frames recorded after a trigger
+
+	mu     sync.Mutex
+	framer framer
+	ring   []byte // whole frames, ring[head:] is the oldest part
+	head   int
+	full   bool
+
+	// the capture in progress
+	w      io.WriteSeeker
+	wf     *wav.WavFile
+	remain int64
+	done   chan error
+}
+
+// NewCapture returns a Capture for PCM data of the given format that keeps
+// the last pre of audio and records post of audio after a trigger.
+func NewCapture(sampleRate, nchans, nbits int, pre, post time.Duration) (*Capture, error) {
+	if sampleRate <= 0 || nchans <= 0 || nbits <= 0 || nbits%8 != 0 {
+		return nil, errors.New("wavrec: invalid format")
+	}
+	if pre < 0 || post < 0 {
+		return nil, errors.New("wavrec: negative capture duration")
+	}
+	hdr := pcmHeader(sampleRate, nchans, nbits)
+	c := &Capture{
+		rate:      sampleRate,
+		nchans:    nchans,
+		nbits:     nbits,
+		blockSize: nchans * nbits / 8,
+		post:      hdr.DurationFrames(post),
+	}
+	c.framer.size = c.blockSize
+	c.ring = make([]byte, hdr.DurationFrames(pre)*int64(c.blockSize))
+	return c, nil
+}
+
+// Write buffers p and, while a capture is in progress, records it. A
+// partial frame at the end of p is held until the rest of it arrives. Errors
+// writing the capture are reported through the channel returned by Trigger,
+// so Write never fails.
+func (c *Capture) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.framer.split(p, c.write)
+	return len(p), nil
+}
+
+// write handles whole frames.
+func (c *Capture) write(p []byte) error {
+	if c.wf != nil {
+		n := min(int64(len(p)/c.blockSize), c.remain)
+		_, err := c.wf.Data.PCMWriter().Write(p[:n*int64(c.blockSize)])
+		if c.remain -= n; err != nil || c.remain == 0 {
+			c.finish(err)
+		}
+	}
+	c.push(p)
+	return nil
+}
+
+// push appends p to the ring, dropping the oldest frames.
+func (c *Capture) push(p []byte) {
+	if len(c.ring) == 0 {
+		return
+	}
+	if len(p) >= len(c.ring) {
+		copy(c.ring, p[len(p)-len(c.ring):])
+		c.head, c.full = 0, true
+		return
+	}
+	n := copy(c.ring[c.head:], p)
+	copy(c.ring, p[n:])
+	if c.head+len(p) >= len(c.ring) {
+		c.full = true
+	}
+	c.head = (c.head + len(p)) % len(c.ring)
+}
+
+// Buffered returns the number of frames in the ring buffer.
+func (c *Capture) Buffered() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.full {
+		return int64(len(c.ring) / c.blockSize)
+	}
+	return int64(c.head / c.blockSize)
+}
+
+// Trigger starts a capture into w: the buffered audio is written at once and
+// the audio written afterwards is recorded until the post-trigger duration
+// is reached. The returned channel receives the result once the file is
+// finalized and, if w is an io.Closer, closed. Only one capture can be in
+// progress at a time.
+func (c *Capture) Trigger(w io.WriteSeeker) (<-chan error, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.wf != nil {
+		return nil, ErrCapturing
+	}
+	wf, err := wav.Create(w, c.rate, c.nchans, c.nbits)
+	if err != nil {
+		return nil, err
+	}
+	c.w, c.wf, c.remain = w, wf, c.post
+	c.done = make(chan error, 1)
+	done := c.done
+	pcm := wf.Data.PCMWriter()
+	if c.full {
+		_, err = pcm.Write(c.ring[c.head:])
+	}
+	if err == nil {
+		_, err = pcm.Write(c.ring[:c.head])
+	}
+	if err != nil || c.remain == 0 {
+		c.finish(err)
+	}
+	return done, nil
+}
+
+// Stop finishes the capture in progress, if any, with the audio recorded so
+// far.
+func (c *Capture) Stop() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.wf != nil {
+		c.finish(nil)
+	}
+}
+
+// finish finalizes the capture in progress and reports its result.
+func (c *Capture) finish(err error) {
+	if err == nil {
+		_, err = c.wf.Encode(c.w)
+	}
+	if cerr := closeWriter(c.w); err == nil {
+		err = cerr
+	}
+	c.done <- err
+	c.w, c.wf, c.done = nil, nil, nil
+}
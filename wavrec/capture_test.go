@@ -0,0 +1,166 @@
+package wavrec
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cakturk/pkg/wav"
+)
+
+func writeChunks(t *testing.T, w io.Writer, p []byte, size int) {
+	t.Helper()
+	for len(p) > 0 {
+		n := min(size, len(p))
+		if _, err := w.Write(p[:n]); err != nil {
+			t.Fatal(err)
+		}
+		p = p[n:]
+	}
+}
+
+func TestCapture(t *testing.T) {
+	const blockSize = 4 // 16-bit stereo
+	c, err := NewCapture(1000, 2, 16, 100*time.Millisecond, 50*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := pcmBytes(400 * blockSize)
+	writeChunks(t, c, src[:300*blockSize+2], 13)
+	if n := c.Buffered(); n != 100 {
+		t.Errorf("Buffered() = %d, want 100", n)
+	}
+
+	name := filepath.Join(t.TempDir(), "event.wav")
+	f, err := os.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	done, err := c.Trigger(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Trigger(&writeSeeker{}); err != ErrCapturing {
+		t.Errorf("second Trigger() = %v, want ErrCapturing", err)
+	}
+	writeChunks(t, c, src[300*blockSize+2:], 9)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	default:
+		t.Fatal("capture not finished")
+	}
+
+	wf, got := readPCM(t, name)
+	if wf.NumFrames() != 150 {
+		t.Errorf("captured %d frames, want 150", wf.NumFrames())
+	}
+	// 100 frames before the trigger, the one it split and 49 after it
+	if want := src[200*blockSize : 350*blockSize]; !bytes.Equal(got, want) {
+		t.Error("captured samples differ")
+	}
+}
+
+func TestCaptureShort(t *testing.T) {
+	c, err := NewCapture(1000, 1, 8, time.Second, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := pcmBytes(30)
+	writeChunks(t, c, src[:20], 20)
+	w := &writeSeeker{}
+	done, err := c.Trigger(w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeChunks(t, c, src[20:], 3)
+	c.Stop()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if !w.closed {
+		t.Error("capture not closed")
+	}
+	wf, err := wav.Decode(bytes.NewReader(w.buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := io.ReadAll(wf.Data.PCMReader())
+	if !bytes.Equal(got, src) {
+		t.Errorf("captured %v, want %v", got, src)
+	}
+	c.Stop() // no capture in progress
+}
+
+type failWriter struct{ writeSeeker }
+
+func (w *failWriter) Write(p []byte) (int, error) {
+	if w.pos >= 44 {
+		return 0, errors.New("disk full")
+	}
+	return w.writeSeeker.Write(p)
+}
+
+func TestCaptureError(t *testing.T) {
+	c, err := NewCapture(1000, 1, 16, 0, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	done, err := c.Trigger(&failWriter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Write(pcmBytes(10)); err != nil {
+		t.Fatalf("Write() = %v", err)
+	}
+	if err := <-done; err == nil {
+		t.Error("capture error not reported")
+	}
+	// the capture slot is free again
+	if _, err := c.Trigger(&writeSeeker{}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCaptureConcurrent(t *testing.T) {
+	c, err := NewCapture(8000, 2, 16, 10*time.Millisecond, 10*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stop := make(chan struct{})
+	go func() {
+		p := pcmBytes(333)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				c.Write(p)
+			}
+		}
+	}()
+	for i := 0; i < 20; i++ {
+		w := &writeSeeker{}
+		done, err := c.Trigger(w)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := <-done; err != nil {
+			t.Fatal(err)
+		}
+		wf, err := wav.Decode(bytes.NewReader(w.buf))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n := wf.NumFrames(); n < 80 || n > 160 {
+			t.Errorf("captured %d frames", n)
+		}
+	}
+	close(stop)
+}
@@ -0,0 +1,285 @@
+// Package wavrec records streams of PCM samples into wav files.
+package wavrec
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/cakturk/pkg/wav"
+)
+
+// RotateOptions controls a Rotator.
+type RotateOptions struct {
+	// MaxDuration and MaxBytes limit the length of each file, MaxBytes
+	// counting PCM bytes only. A file is finished when it reaches either
+	// limit. At least one of them must be set. Files are also finished
+	// before outgrowing the 4 GiB a wav header can describe.
+	MaxDuration time.Duration
+	MaxBytes    int64
+
+	// Name is a text/template producing the path of each file. It is
+	// executed with a RotatedFile, e.g.
+	// "rec-{{.Time.Format \"20060102-150405\"}}.wav".
+	Name string
+	// Create opens each file; os.Create if nil. Files implementing
+	// io.Closer are closed once finished.
+	Create func(name string) (io.WriteSeeker, error)
+	// Start is the time of the first frame. It defaults to the time of
+	// the first write. File times are derived from Start and the number
+	// of frames recorded, not from the wall clock.
+	Start time.Time
+}
+
+// RotatedFile describes a file written by a Rotator. Index starts at 1.
+type RotatedFile struct {
+	Index      int
+	Name       string
+	Time       time.Time // time of the first frame
+	StartFrame int64     // first frame, counted from the start of the recording
+	Frames     int64
+}
+
+// Rotator is an io.WriteCloser recording interleaved PCM data into a
+// sequence of wav files, starting a new file whenever a limit is reached.
+// Files are cut at frame boundaries with no gap between them; a partial
+// frame at the end of a write is held until the rest of it arrives.
+type Rotator struct {
+	opts      RotateOptions
+	tmpl      *template.Template
+	create    func(name string) (io.WriteSeeker, error)
+	rate      int
+	nchans    int
+	nbits     int
+	blockSize int
+	maxFrames int64
+	hdr       *wav.WavFile // format of the files, for time conversions
+
+	w      io.WriteSeeker
+	wf     *wav.WavFile
+	cur    RotatedFile
+	frames int64 // frames recorded so far
+	framer framer
+	files  []RotatedFile
+	err    error
+}
+
+// NewRotator returns a Rotator writing PCM wav files of the given format.
+// opts must give at least Name and a limit.
+func NewRotator(sampleRate, nchans, nbits int, opts *RotateOptions) (*Rotator, error) {
+	if sampleRate <= 0 || nchans <= 0 || nbits <= 0 || nbits%8 != 0 {
+		return nil, errors.New("wavrec: invalid format")
+	}
+	if opts == nil {
+		return nil, errors.New("wavrec: nil options")
+	}
+	if opts.Name == "" {
+		return nil, errors.New("wavrec: empty name template")
+	}
+	tmpl, err := template.New("name").Parse(opts.Name)
+	if err != nil {
+		return nil, fmt.Errorf("wavrec: %w", err)
+	}
+	r := &Rotator{
+		opts:      *opts,
+		tmpl:      tmpl,
+		create:    opts.Create,
+		rate:      sampleRate,
+		nchans:    nchans,
+		nbits:     nbits,
+		blockSize: nchans * nbits / 8,
+		hdr:       pcmHeader(sampleRate, nchans, nbits),
+	}
+	r.framer.size = r.blockSize
+	if r.create == nil {
+		r.create = func(name string) (io.WriteSeeker, error) {
+			return os.Create(name)
+		}
+	}
+	if opts.MaxDuration > 0 {
+		r.maxFrames = r.hdr.DurationFrames(opts.MaxDuration)
+	}
+	if opts.MaxBytes > 0 {
+		n := opts.MaxBytes / int64(r.blockSize)
+		if r.maxFrames == 0 || n < r.maxFrames {
+			r.maxFrames = n
+		}
+	}
+	if r.maxFrames <= 0 {
+		return nil, errors.New("wavrec: no limit or limit shorter than a frame")
+	}
+	r.maxFrames = min(r.maxFrames, maxDataBytes/int64(r.blockSize))
+	return r, nil
+}
+
+// maxDataBytes is the largest data chunk whose size and the size of the
+// RIFF chunk holding it both fit in their uint32 header fields.
+var maxDataBytes int64 = math.MaxUint32 - wav.RIFFHdrSize - wav.FmtChunkSize - wav.DataChunkHdrSize
+
+// pcmHeader returns a header describing the PCM format, used for time
+// conversions.
+func pcmHeader(sampleRate, nchans, nbits int) *wav.WavFile {
+	return &wav.WavFile{Fmt: wav.FmtChunk{
+		NumChans:      uint16(nchans),
+		SampleRate:    uint32(sampleRate),
+		BlockAlign:    uint16(nchans * nbits / 8),
+		BitsPerSample: uint16(nbits),
+	}}
+}
+
+// Write records p. It always consumes all of p unless an error occurs.
+func (r *Rotator) Write(p []byte) (int, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	if err := r.framer.split(p, r.write); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// write records whole frames.
+func (r *Rotator) write(p []byte) error {
+	for len(p) > 0 {
+		if r.wf == nil {
+			if err := r.open(); err != nil {
+				r.err = err
+				return err
+			}
+		}
+		frames := min(int64(len(p)/r.blockSize), r.maxFrames-r.cur.Frames)
+		m := int(frames) * r.blockSize
+		if _, err := r.wf.Data.PCMWriter().Write(p[:m]); err != nil {
+			r.err = err
+			return err
+		}
+		p = p[m:]
+		r.cur.Frames += frames
+		r.frames += frames
+		if r.cur.Frames == r.maxFrames {
+			if err := r.finish(); err != nil {
+				r.err = err
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (r *Rotator) open() error {
+	if r.opts.Start.IsZero() {
+		r.opts.Start = time.Now()
+	}
+	r.cur = RotatedFile{
+		Index:      len(r.files) + 1,
+		Time:       r.opts.Start.Add(r.hdr.FrameDuration(r.frames)),
+		StartFrame: r.frames,
+	}
+	var name strings.Builder
+	if err := r.tmpl.Execute(&name, r.cur); err != nil {
+		return fmt.Errorf("wavrec: %w", err)
+	}
+	r.cur.Name = name.String()
+	w, err := r.create(r.cur.Name)
+	if err != nil {
+		return err
+	}
+	wf, err := wav.Create(w, r.rate, r.nchans, r.nbits)
+	if err != nil {
+		closeWriter(w)
+		return err
+	}
+	r.w, r.wf = w, wf
+	return nil
+}
+
+// finish finalizes the header of the current file and closes it.
+func (r *Rotator) finish() error {
+	_, err := r.wf.Encode(r.w)
+	if cerr := closeWriter(r.w); err == nil {
+		err = cerr
+	}
+	r.w, r.wf = nil, nil
+	if err != nil {
+		return err
+	}
+	r.files = append(r.files, r.cur)
+	return nil
+}
+
+var errClosed = errors.New("wavrec: write to closed recorder")
+
+// Close finishes the current file. A partial frame left over from the last
+// write is discarded and reported as an error.
+func (r *Rotator) Close() error {
+	if r.err == errClosed {
+		return nil
+	}
+	if r.err != nil {
+		return r.err
+	}
+	if r.wf != nil {
+		if err := r.finish(); err != nil {
+			r.err = err
+			return err
+		}
+	}
+	r.err = errClosed
+	return r.framer.close()
+}
+
+// Files returns the files finished so far.
+func (r *Rotator) Files() []RotatedFile {
+	return r.files
+}
+
+// framer cuts a byte stream into whole frames, holding a trailing partial
+// frame until the next call.
+type framer struct {
+	size    int
+	partial []byte
+}
+
+// split calls fn with the whole frames of p, preceded by the frame completed
+// by the start of p.
+func (f *framer) split(p []byte, fn func([]byte) error) error {
+	if len(f.partial) > 0 {
+		need := min(f.size-len(f.partial), len(p))
+		f.partial = append(f.partial, p[:need]...)
+		p = p[need:]
+		if len(f.partial) < f.size {
+			return nil
+		}
+		if err := fn(f.partial); err != nil {
+			return err
+		}
+		f.partial = f.partial[:0]
+	}
+	whole := len(p) - len(p)%f.size
+	if err := fn(p[:whole]); err != nil {
+		return err
+	}
+	f.partial = append(f.partial, p[whole:]...)
+	return nil
+}
+
+// close reports a discarded partial frame.
+func (f *framer) close() error {
+	if n := len(f.partial); n > 0 {
+		f.partial = f.partial[:0]
+		return fmt.Errorf("wavrec: discarded %d bytes of a partial frame", n)
+	}
+	return nil
+}
+
+func closeWriter(w io.WriteSeeker) error {
+	if c, ok := w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
@@ -0,0 +1,221 @@
+package wavrec
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cakturk/pkg/wav"
+)
+
+func pcmBytes(n int) []byte {
+	p := make([]byte, n)
+	for i := range p {
+		p[i] = byte(i*7 + i/251)
+	}
+	return p
+}
+
+func readPCM(t *testing.T, name string) (*wav.WavFile, []byte) {
+	t.Helper()
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	wf, err := wav.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := io.ReadAll(wf.Data.PCMReader())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return wf, p
+}
+
+func TestRotator(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	r, err := NewRotator(8000, 2, 16, &RotateOptions{
+		MaxDuration: 100 * time.Millisecond,
+		Name:        filepath.Join(dir, `{{.Index}}-{{.Time.Format "150405.000"}}.wav`),
+		Start:       start,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := pcmBytes(2000 * 4)
+	// odd sized writes split frames between calls
+	for p := src; len(p) > 0; {
+		n := min(7, len(p))
+		if m, err := r.Write(p[:n]); err != nil || m != n {
+			t.Fatalf("Write() = %d, %v", m, err)
+		}
+		p = p[n:]
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Close(); err != nil {
+		t.Errorf("second Close() = %v", err)
+	}
+	if _, err := r.Write(src[:4]); err == nil {
+		t.Error("Write() after Close succeeded")
+	}
+
+	files := r.Files()
+	wantFrames := []int64{800, 800, 400}
+	if len(files) != len(wantFrames) {
+		t.Fatalf("got %d files, want %d", len(files), len(wantFrames))
+	}
+	var got []byte
+	for i, f := range files {
+		tm := start.Add(time.Duration(i) * 100 * time.Millisecond)
+		want := RotatedFile{
+			Index:      i + 1,
+			Name:       filepath.Join(dir, fmt.Sprintf("%d-%s.wav", i+1, tm.Format("150405.000"))),
+			Time:       tm,
+			StartFrame: int64(i) * 800,
+			Frames:     wantFrames[i],
+		}
+		if f != want {
+			t.Errorf("file %d = %+v, want %+v", i, f, want)
+		}
+		wf, p := readPCM(t, f.Name)
+		if wf.NumFrames() != f.Frames || wf.Fmt.NumChans != 2 || wf.Fmt.SampleRate != 8000 {
+			t.Errorf("file %d: %d frames, fmt %+v", i, wf.NumFrames(), wf.Fmt)
+		}
+		got = append(got, p...)
+	}
+	if !bytes.Equal(got, src) {
+		t.Error("recorded samples differ")
+	}
+}
+
+func TestRotatorMaxBytes(t *testing.T) {
+	var bufs []*writeSeeker
+	r, err := NewRotator(8000, 3, 24, &RotateOptions{
+		MaxDuration: time.Second,
+		MaxBytes:    100, // 11 frames of 9 bytes
+		Name:        "{{.Index}}",
+		Create: func(string) (io.WriteSeeker, error) {
+			bufs = append(bufs, &writeSeeker{})
+			return bufs[len(bufs)-1], nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := pcmBytes(25*9 + 4)
+	if _, err := r.Write(src); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Close(); err == nil {
+		t.Error("Close() with a partial frame succeeded")
+	}
+	var frames []int64
+	for _, f := range r.Files() {
+		frames = append(frames, f.Frames)
+	}
+	if fmt.Sprint(frames) != "[11 11 3]" || len(bufs) != 3 {
+		t.Errorf("frames = %v in %d files", frames, len(bufs))
+	}
+	for i, b := range bufs {
+		if !b.closed {
+			t.Errorf("file %d not closed", i)
+		}
+	}
+}
+
+func TestRotatorMaxDataSize(t *testing.T) {
+	// 12h at 48kHz, stereo, 24-bit is about 12.4 GB
+	r, err := NewRotator(48000, 2, 24, &RotateOptions{MaxDuration: 12 * time.Hour, Name: "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := int64(math.MaxUint32-44) / 6; r.maxFrames != want {
+		t.Errorf("maxFrames = %d, want %d", r.maxFrames, want)
+	}
+
+	defer func(n int64) { maxDataBytes = n }(maxDataBytes)
+	maxDataBytes = 100 // 11 frames of 9 bytes
+	var bufs []*writeSeeker
+	r, err = NewRotator(8000, 3, 24, &RotateOptions{
+		MaxDuration: time.Hour,
+		Name:        "{{.Index}}",
+		Create: func(string) (io.WriteSeeker, error) {
+			bufs = append(bufs, &writeSeeker{})
+			return bufs[len(bufs)-1], nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Write(pcmBytes(25 * 9)); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	var frames []int64
+	for _, f := range r.Files() {
+		frames = append(frames, f.Frames)
+	}
+	if fmt.Sprint(frames) != "[11 11 3]" {
+		t.Errorf("frames = %v", frames)
+	}
+}
+
+func TestNewRotatorErrors(t *testing.T) {
+	tests := []RotateOptions{
+		{Name: "x"},
+		{MaxBytes: 3, Name: "x"},
+		{MaxDuration: time.Second},
+		{MaxDuration: time.Second, Name: "{{"},
+	}
+	for _, opts := range tests {
+		if _, err := NewRotator(8000, 2, 16, &opts); err == nil {
+			t.Errorf("NewRotator(%+v) succeeded", opts)
+		}
+	}
+	if _, err := NewRotator(8000, 2, 16, nil); err == nil {
+		t.Error("NewRotator with nil options succeeded")
+	}
+}
+
+// writeSeeker is an in-memory io.WriteSeeker.
+type writeSeeker struct {
+	buf    []byte
+	pos    int
+	closed bool
+}
+
+func (w *writeSeeker) Write(p []byte) (int, error) {
+	if end := w.pos + len(p); end > len(w.buf) {
+		w.buf = append(w.buf, make([]byte, end-len(w.buf))...)
+	}
+	w.pos += copy(w.buf[w.pos:], p)
+	return len(p), nil
+}
+
+func (w *writeSeeker) Seek(off int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		off += int64(w.pos)
+	case io.SeekEnd:
+		off += int64(len(w.buf))
+	}
+	w.pos = int(off)
+	return off, nil
+}
+
+func (w *writeSeeker) Close() error {
+	w.closed = true
+	return nil
+}
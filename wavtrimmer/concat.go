@@ -0,0 +1,252 @@
+package wavtrimmer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	cwav "github.com/cakturk/pkg/wav"
+)
+
+// ConcatOptions controls how Concat joins its inputs. Gap and Crossfade are
+// mutually exclusive.
+type ConcatOptions struct {
+	// Gap is the amount of silence inserted between two segments.
+	Gap time.Duration
+	// Crossfade is the length of the equal-power crossfade between two
+	// segments. It is shortened for segments shorter than twice its length.
+	Crossfade time.Duration
+	// Convert allows inputs whose sample format or channel count differ
+	// from the first input. They are converted to the format of the first
+	// one. Mono inputs are copied to every channel and multichannel inputs
+	// are downmixed for a mono output. Sample rates must always match.
+	Convert bool
+	// NoCue disables the cue point which is otherwise added at each join.
+	NoCue bool
+}
+
+// Concat joins the PCM samples of the wav files in srcs, in order, and writes
+// them into a new wav file which uses the format of the first input.
+func Concat(w io.WriteSeeker, srcs []io.ReadSeeker, opts *ConcatOptions) error {
+	if len(srcs) == 0 {
+		return errors.New("concat: no input files")
+	}
+	if opts == nil {
+		opts = &ConcatOptions{}
+	}
+	if opts.Gap < 0 || opts.Crossfade < 0 {
+		return errors.New("concat: negative gap or crossfade")
+	}
+	if opts.Gap > 0 && opts.Crossfade > 0 {
+		return errors.New("concat: gap and crossfade are mutually exclusive")
+	}
+	segs := make([]*segment, len(srcs))
+	for i, r := range srcs {
+		wf, err := cwav.Decode(r)
+		if err != nil {
+			return fmt.Errorf("concat: input %d: %w", i, err)
+		}
+		f := &wf.Fmt
+		if i > 0 {
+			f = &segs[0].Fmt
+		}
+		if segs[i], err = newSegment(wf, f, opts.Convert); err != nil {
+			return fmt.Errorf("concat: input %d: %w", i, err)
+		}
+	}
+
+	f := segs[0].Fmt
+	dst, err := cwav.CreateFmt(w, f)
+	if err != nil {
+		return err
+	}
+	fw, err := dst.FrameWriter()
+	if err != nil {
+		return err
+	}
+	nchans := int(f.NumChans)
+	gap := segs[0].DurationFrames(opts.Gap)
+	xfade := segs[0].DurationFrames(opts.Crossfade)
+
+	var (
+		cue  = cwav.NewCueChunk()
+		pos  int64 // frames written so far
+		tail []float64
+		buf  = make([]float64, copyFrames*nchans)
+	)
+	for i, s := range segs {
+		head := int64(len(tail) / nchans)
+		if i > 0 {
+			if gap > 0 {
+				if err := writeSilence(fw, gap, nchans); err != nil {
+					return err
+				}
+				pos += gap
+			}
+			cue.Add(uint32(pos))
+			if head > 0 {
+				mix := make([]float64, len(tail))
+				if _, err := s.readFull(mix); err != nil {
+					return err
+				}
+				crossfade(mix, tail, nchans)
+				if _, err := fw.WriteFrames(mix); err != nil {
+					return err
+				}
+				pos += head
+			}
+		}
+		var keep int64
+		if i < len(segs)-1 {
+			keep = min(xfade, s.frames/2, segs[i+1].frames/2)
+		}
+		n, err := s.copyTo(fw, s.frames-head-keep, buf)
+		pos += n
+		if err != nil {
+			return err
+		}
+		tail = make([]float64, keep*int64(nchans))
+		if _, err := s.readFull(tail); err != nil {
+			return err
+		}
+	}
+	if !opts.NoCue && len(cue.Points) > 0 {
+		dst.Cue = cue
+	}
+	_, err = dst.Encode(w)
+	return err
+}
+
+// copyFrames is the number of frames processed at once.
+const copyFrames = 4096
+
+// segment reads the frames of a source file converted to the channel layout
+// of the output.
+type segment struct {
+	*cwav.WavFile
+	fr     *cwav.FrameReader
+	m      cwav.Matrix
+	nchans int // output channels
+	frames int64
+	in     []float64
+}
+
+func newSegment(wf *cwav.WavFile, dst *cwav.FmtChunk, convert bool) (*segment, error) {
+	m, err := convMatrix(dst, &wf.Fmt, convert)
+	if err != nil {
+		return nil, err
+	}
+	fr, err := wf.FrameReader()
+	if err != nil {
+		return nil, err
+	}
+	return &segment{
+		WavFile: wf,
+		fr:      fr,
+		m:       m,
+		nchans:  int(dst.NumChans),
+		frames:  wf.NumFrames(),
+	}, nil
+}
+
+// convMatrix checks whether src can be written into a file of format dst and
+// returns the channel matrix needed to do so, or nil if none is needed.
+func convMatrix(dst, src *cwav.FmtChunk, convert bool) (cwav.Matrix, error) {
+	if src.SampleRate != dst.SampleRate {
+		return nil, fmt.Errorf("sample rate %d does not match %d", src.SampleRate, dst.SampleRate)
+	}
+	same := src.Format() == dst.Format() && src.BitsPerSample == dst.BitsPerSample
+	if !convert && (!same || src.NumChans != dst.NumChans) {
+		return nil, fmt.Errorf(
+			"format %d/%d-bit/%dch does not match %d/%d-bit/%dch",
+			src.Format(), src.BitsPerSample, src.NumChans,
+			dst.Format(), dst.BitsPerSample, dst.NumChans,
+		)
+	}
+	in, out := int(src.NumChans), int(dst.NumChans)
+	switch {
+	case in == out:
+		return nil, nil
+	case in == 1:
+		m := make(cwav.Matrix, out)
+		for o := range m {
+			m[o] = []float64{1}
+		}
+		return m, nil
+	case out == 1:
+		return cwav.DownmixMatrix(in), nil
+	}
+	return nil, fmt.Errorf("cannot convert %d channels to %d", in, out)
+}
+
+// ReadFrames reads up to len(p)/NumChans frames of the output layout into p.
+func (s *segment) ReadFrames(p []float64) (int, error) {
+	if s.m == nil {
+		return s.fr.ReadFrames(p)
+	}
+	in, out := len(s.m[0]), len(s.m)
+	frames := len(p) / out
+	if cap(s.in) < frames*in {
+		s.in = make([]float64, frames*in)
+	}
+	n, err := s.fr.ReadFrames(s.in[:frames*in])
+	s.m.Apply(p[:n*out], s.in[:n*in])
+	return n, err
+}
+
+// readFull reads exactly len(p)/NumChans frames into p.
+func (s *segment) readFull(p []float64) (int, error) {
+	n, err := cwav.ReadFullFrames(s, p, s.nchans)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+// copyTo copies n frames of the segment into fw.
+func (s *segment) copyTo(fw *cwav.FrameWriter, n int64, buf []float64) (int64, error) {
+	nchans := s.nchans
+	var written int64
+	for written < n {
+		p := buf[:min(int64(len(buf)/nchans), n-written)*int64(nchans)]
+		got, err := s.readFull(p)
+		if got > 0 {
+			if _, err := fw.WriteFrames(p[:got*nchans]); err != nil {
+				return written, err
+			}
+			written += int64(got)
+		}
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// crossfade mixes the outgoing frames in tail into the incoming frames in
+// head using equal-power gains.
+func crossfade(head, tail []float64, nchans int) {
+	n := len(head) / nchans
+	for i := 0; i < n; i++ {
+		t := (float64(i) + 0.5) / float64(n) * math.Pi / 2
+		in, out := math.Sin(t), math.Cos(t)
+		for c := 0; c < nchans; c++ {
+			j := i*nchans + c
+			head[j] = head[j]*in + tail[j]*out
+		}
+	}
+}
+
+func writeSilence(fw *cwav.FrameWriter, frames int64, nchans int) error {
+	zero := make([]float64, min(frames, copyFrames)*int64(nchans))
+	for frames > 0 {
+		n := min(frames, copyFrames)
+		if _, err := fw.WriteFrames(zero[:n*int64(nchans)]); err != nil {
+			return err
+		}
+		frames -= n
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+package wavtrimmer
+
+import (
+	"io"
+	"math"
+	"os"
+	"testing"
+	"time"
+
+	cwav "github.com/cakturk/pkg/wav"
+	"github.com/cakturk/pkg/wavgen"
+)
+
+func tempFile(t testing.TB) *os.File {
+	t.Helper()
+	f, err := os.CreateTemp(t.TempDir(), "*.wav")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+	return f
+}
+
+// makeWav creates a wav file holding the given interleaved samples and
+// returns it rewound to the beginning.
+func makeWav(t testing.TB, sampleRate, nchans, nbits int, samples []float64) *os.File {
+	t.Helper()
+	f := tempFile(t)
+	sigs := make([]wavgen.Signal, nchans)
+	for c := range sigs {
+		sigs[c] = wavgen.SignalFunc(func(n int64, _ int) float64 {
+			return samples[int(n)*nchans+c]
+		})
+	}
+	format := wavgen.Format{SampleRate: sampleRate, Channels: nchans, Bits: nbits}
+	if err := wavgen.Write(f, format, int64(len(samples)/nchans), sigs...); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+// readWav decodes r and returns its header and samples.
+func readWav(t testing.TB, r io.ReadSeeker) (*cwav.WavFile, []float64) {
+	t.Helper()
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	wf, err := cwav.Decode(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fr, err := wf.FrameReader()
+	if err != nil {
+		t.Fatal(err)
+	}
+	nchans := int(wf.Fmt.NumChans)
+	var all []float64
+	p := make([]float64, 1024*nchans)
+	for {
+		n, err := fr.ReadFrames(p)
+		all = append(all, p[:n*nchans]...)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return wf, all
+}
+
+func constSamples(n int, v float64) []float64 {
+	p := make([]float64, n)
+	for i := range p {
+		p[i] = v
+	}
+	return p
+}
+
+func TestConcat(t *testing.T) {
+	a := makeWav(t, 1000, 1, 16, constSamples(10, 0.5))
+	b := makeWav(t, 1000, 1, 16, constSamples(20, -0.5))
+	out := tempFile(t)
+	err := Concat(out, []io.ReadSeeker{a, b}, &ConcatOptions{Gap: 5 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wf, got := readWav(t, out)
+	want := append(append(constSamples(10, 0.5), make([]float64, 5)...), constSamples(20, -0.5)...)
+	if len(got) != len(want) {
+		t.Fatalf("got %d frames, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("frame %d: got %v, want %v", i, got[i], want[i])
+		}
+	}
+	if wf.Cue == nil || len(wf.Cue.Points) != 1 || wf.Cue.Points[0].SampleOffset != 15 {
+		t.Errorf("unexpected cue chunk: %+v", wf.Cue)
+	}
+}
+
+func TestConcatCrossfade(t *testing.T) {
+	a := makeWav(t, 1000, 2, 16, constSamples(20, 0.5))
+	b := makeWav(t, 1000, 2, 16, constSamples(20, 0.5))
+	c := makeWav(t, 1000, 2, 16, constSamples(8, 0.5))
+	out := tempFile(t)
+	err := Concat(out, []io.ReadSeeker{a, b, c}, &ConcatOptions{Crossfade: 4 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wf, got := readWav(t, out)
+	// 10 + 10 + 4 frames minus overlaps of 4 and 2 frames, the latter
+	// shortened to half of the last segment
+	if frames := len(got) / 2; frames != 18 {
+		t.Errorf("got %d frames, want 18", frames)
+	}
+	if wf.Cue == nil || len(wf.Cue.Points) != 2 ||
+		wf.Cue.Points[0].SampleOffset != 6 || wf.Cue.Points[1].SampleOffset != 14 {
+		t.Errorf("unexpected cue chunk: %+v", wf.Cue)
+	}
+	// equal-power fade of two identical signals never drops below them
+	for i, s := range got {
+		if s < 0.5-1e-4 || s > 0.5*math.Sqrt2+1e-4 {
+			t.Fatalf("sample %d out of range: %v", i, s)
+		}
+	}
+}
+
+func TestConcatConvert(t *testing.T) {
+	a := makeWav(t, 1000, 2, 24, constSamples(4, 0.25))
+	b := makeWav(t, 1000, 1, 8, constSamples(2, 0.5))
+	out := tempFile(t)
+	if err := Concat(out, []io.ReadSeeker{a, b}, nil); err == nil {
+		t.Fatal("expected a format mismatch error")
+	}
+	a.Seek(0, io.SeekStart)
+	b.Seek(0, io.SeekStart)
+	if err := Concat(out, []io.ReadSeeker{a, b}, &ConcatOptions{Convert: true}); err != nil {
+		t.Fatal(err)
+	}
+	wf, got := readWav(t, out)
+	if wf.Fmt.BitsPerSample != 24 || wf.Fmt.NumChans != 2 {
+		t.Errorf("unexpected format: %+v", wf.Fmt)
+	}
+	want := []float64{0.25, 0.25, 0.25, 0.25, 0.5, 0.5, 0.5, 0.5}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	}
+
+	c := makeWav(t, 2000, 2, 24, constSamples(4, 0.25))
+	a.Seek(0, io.SeekStart)
+	err := Concat(tempFile(t), []io.ReadSeeker{a, c}, &ConcatOptions{Convert: true})
+	if err == nil {
+		t.Error("expected a sample rate mismatch error")
+	}
+}
@@ -0,0 +1,170 @@
+package wavtrimmer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	cwav "github.com/cakturk/pkg/wav"
+)
+
+// DeleteOptions controls Delete and DeleteFrames.
+type DeleteOptions struct {
+	// Crossfade is the length of the equal-power crossfade at each join.
+	// Like in Concat it is shortened next to short remaining parts, and
+	// each crossfade makes the output shorter by its length.
+	Crossfade time.Duration
+}
+
+// Delete removes the time ranges [start, end) from the wav file read from r
+// and writes what remains into a new wav file. Boundaries are converted to
+// frames like Trim2's, and -1 is open ended.
+func Delete(r io.ReadSeeker, ranges [][2]time.Duration, w io.WriteSeeker, opts *DeleteOptions) error {
+	src, err := cwav.Decode(r)
+	if err != nil {
+		return err
+	}
+	frames := make([][2]int64, len(ranges))
+	for i, rg := range ranges {
+		start, end, err := durationRange(rg[0], rg[1], src)
+		if err != nil {
+			return fmt.Errorf("range %d: %w", i, err)
+		}
+		frames[i] = [2]int64{start, end}
+	}
+	return deleteDecoded(src, frames, w, opts)
+}
+
+// DeleteFrames removes the frame ranges [start, end) from the wav file read
+// from r and writes what remains into a new wav file. Ranges may overlap
+// and come in any order. The LIST/INFO chunk is copied and cue points are
+// moved along with the audio they point at; points inside a removed range
+// are dropped.
+func DeleteFrames(r io.ReadSeeker, ranges [][2]int64, w io.WriteSeeker, opts *DeleteOptions) error {
+	src, err := cwav.Decode(r)
+	if err != nil {
+		return err
+	}
+	return deleteDecoded(src, ranges, w, opts)
+}
+
+func deleteDecoded(src *cwav.WavFile, ranges [][2]int64, w io.WriteSeeker, opts *DeleteOptions) error {
+	if opts == nil {
+		opts = &DeleteOptions{}
+	}
+	if opts.Crossfade < 0 {
+		return errors.New("trim: negative crossfade")
+	}
+	kept, err := keptRanges(ranges, src.NumFrames())
+	if err != nil {
+		return err
+	}
+	if len(kept) == 0 {
+		return errors.New("trim: nothing left after deleting")
+	}
+
+	// xfades[i] is the length of the crossfade between kept[i] and
+	// kept[i+1]
+	xfade := src.DurationFrames(opts.Crossfade)
+	xfades := make([]int64, len(kept))
+	for i := range kept[:len(kept)-1] {
+		xfades[i] = min(xfade, (kept[i][1]-kept[i][0])/2, (kept[i+1][1]-kept[i+1][0])/2)
+	}
+
+	dst, err := cwav.CreateFmt(w, src.Fmt)
+	if err != nil {
+		return err
+	}
+	fw, err := dst.FrameWriter()
+	if err != nil {
+		return err
+	}
+	nchans := int(src.Fmt.NumChans)
+	var (
+		tail []float64
+		buf  = make([]float64, copyFrames*nchans)
+	)
+	for i, k := range kept {
+		fr, err := src.RangeFrameReader(k[0], k[1])
+		if err != nil {
+			return err
+		}
+		s := &segment{WavFile: src, fr: fr, nchans: nchans, frames: k[1] - k[0]}
+		head := int64(len(tail) / nchans)
+		if head > 0 {
+			mix := make([]float64, len(tail))
+			if _, err := s.readFull(mix); err != nil {
+				return err
+			}
+			crossfade(mix, tail, nchans)
+			if _, err := fw.WriteFrames(mix); err != nil {
+				return err
+			}
+		}
+		if _, err := s.copyTo(fw, s.frames-head-xfades[i], buf); err != nil {
+			return err
+		}
+		tail = make([]float64, xfades[i]*int64(nchans))
+		if _, err := s.readFull(tail); err != nil {
+			return err
+		}
+	}
+	dst.List = copyList(src.List)
+	if src.Cue != nil {
+		dst.Cue = shiftCues(src.Cue, kept, xfades)
+	}
+	_, err = dst.Encode(w)
+	return err
+}
+
+// keptRanges validates the ranges to delete from a file of total frames and
+// returns the ranges which remain, in order.
+func keptRanges(ranges [][2]int64, total int64) ([][2]int64, error) {
+	del := make([][2]int64, len(ranges))
+	for i, rg := range ranges {
+		start, end, err := frameRange(rg[0], rg[1], total)
+		if err != nil {
+			return nil, fmt.Errorf("range %d: %w", i, err)
+		}
+		del[i] = [2]int64{start, end}
+	}
+	sort.Slice(del, func(i, j int) bool { return del[i][0] < del[j][0] })
+	var (
+		kept [][2]int64
+		pos  int64
+	)
+	for _, d := range del {
+		if d[0] > pos {
+			kept = append(kept, [2]int64{pos, d[0]})
+		}
+		pos = max(pos, d[1])
+	}
+	if pos < total {
+		kept = append(kept, [2]int64{pos, total})
+	}
+	return kept, nil
+}
+
+// shiftCues returns the cue points of c which fall into the kept ranges,
+// moved to their position in the output.
+func shiftCues(c *cwav.CueChunk, kept [][2]int64, xfades []int64) *cwav.CueChunk {
+	out := &cwav.CueChunk{SubChunkID: cwav.CUE}
+	for _, p := range c.Points {
+		var pos int64 // output position of the current kept range
+		for i, k := range kept {
+			if off := int64(p.SampleOffset); off >= k[0] && off < k[1] {
+				p.SampleOffset = uint32(pos + off - k[0])
+				p.Position = p.SampleOffset
+				out.Points = append(out.Points, p)
+				break
+			}
+			pos += k[1] - k[0] - xfades[i]
+		}
+	}
+	if len(out.Points) == 0 {
+		return nil
+	}
+	return out
+}
@@ -0,0 +1,107 @@
+package wavtrimmer
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"testing"
+	"time"
+
+	cwav "github.com/cakturk/pkg/wav"
+)
+
+func TestDeleteFrames(t *testing.T) {
+	src := frameSamples(1000, 2)
+	in := makeWav(t, 1000, 2, 16, src)
+	ranges := [][2]int64{{900, -1}, {100, 200}, {150, 300}}
+
+	out := tempFile(t)
+	if err := DeleteFrames(in, ranges, out, nil); err != nil {
+		t.Fatal(err)
+	}
+	_, got := readWav(t, out)
+	want := append(append([]float64{}, src[:100*2]...), src[300*2:900*2]...)
+	if !equalSamples(got, want) {
+		t.Errorf("got %d frames, want %d", len(got)/2, len(want)/2)
+	}
+
+	in.Seek(0, io.SeekStart)
+	out = tempFile(t)
+	err := Delete(in, [][2]time.Duration{{900 * time.Millisecond, -1}, {100 * time.Millisecond, 300 * time.Millisecond}},
+		out, &DeleteOptions{Crossfade: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, got = readWav(t, out)
+	if len(got) != 690*2 {
+		t.Fatalf("got %d frames, want 690", len(got)/2)
+	}
+	for i := 0; i < 10; i++ {
+		a := (float64(i) + 0.5) / 10 * math.Pi / 2
+		for c := 0; c < 2; c++ {
+			want := src[(90+i)*2+c]*math.Cos(a) + src[(300+i)*2+c]*math.Sin(a)
+			if g := got[(90+i)*2+c]; math.Abs(g-want) > 1.0/16384 {
+				t.Errorf("frame %d: got %v, want %v", 90+i, g, want)
+			}
+		}
+	}
+	if !equalSamples(got[100*2:], src[310*2:900*2]) {
+		t.Error("samples after the crossfade differ")
+	}
+}
+
+func TestDeleteCues(t *testing.T) {
+	f := tempFile(t)
+	wf, err := cwav.Create(f, 1000, 1, 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fw, _ := wf.FrameWriter()
+	fw.WriteFrames(frameSamples(1000, 1))
+	wf.Cue = cwav.NewCueChunk(50, 120, 400, 950)
+	if _, err := wf.Encode(f); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		xfade time.Duration
+		want  string
+	}{
+		{0, "[{1 50} {3 200}]"},
+		{10 * time.Millisecond, "[{1 50} {3 190}]"},
+	} {
+		f.Seek(0, io.SeekStart)
+		out := tempFile(t)
+		err := DeleteFrames(f, [][2]int64{{100, 300}, {900, -1}}, out, &DeleteOptions{Crossfade: tt.xfade})
+		if err != nil {
+			t.Fatal(err)
+		}
+		dst, _ := readWav(t, out)
+		var got []string
+		for _, p := range dst.Cue.Points {
+			if p.Position != p.SampleOffset {
+				t.Errorf("cue %d: position %d, sample offset %d", p.ID, p.Position, p.SampleOffset)
+			}
+			got = append(got, fmt.Sprintf("{%d %d}", p.ID, p.SampleOffset))
+		}
+		if fmt.Sprint(got) != tt.want {
+			t.Errorf("crossfade %s: cues = %v, want %s", tt.xfade, got, tt.want)
+		}
+	}
+}
+
+func TestDeleteErrors(t *testing.T) {
+	in := makeWav(t, 1000, 1, 16, frameSamples(100, 1))
+	tests := [][][2]int64{
+		{{0, 50}, {50, -1}},
+		{{-1, -1}},
+		{{10, 101}},
+		{{20, 10}},
+	}
+	for _, ranges := range tests {
+		in.Seek(0, io.SeekStart)
+		if err := DeleteFrames(in, ranges, tempFile(t), nil); err == nil {
+			t.Errorf("DeleteFrames(%v) succeeded", ranges)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package wavtrimmer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+
+	cwav "github.com/cakturk/pkg/wav"
+)
+
+// Cut is an entry of a cut list: the frames [Start, End) of the source are
+// written into a new wav file named Name. -1 is open ended as in TrimFrames.
+type Cut struct {
+	Start, End int64
+	Name       string
+}
+
+// ExtractOptions controls Extract.
+type ExtractOptions struct {
+	// Create opens the output of each cut; os.Create if nil. Outputs
+	// implementing io.Closer are closed once written.
+	Create func(name string) (io.WriteSeeker, error)
+	// Depth is the number of blocks of audio queued for each output. It
+	// bounds the memory used to about Depth*4096 frames for each cut
+	// being written at the same time. It defaults to 4.
+	Depth int
+}
+
+// Extract writes every cut of the wav file read from r into its own file.
+// The source is read only once, in order, skipping the parts not covered by
+// any cut, so cuts may overlap. Outputs are opened when the source reaches
+// their start and are written concurrently.
+func Extract(r io.ReadSeeker, cuts []Cut, opts *ExtractOptions) error {
+	if opts == nil {
+		opts = &ExtractOptions{}
+	}
+	create := opts.Create
+	if create == nil {
+		create = func(name string) (io.WriteSeeker, error) {
+			return os.Create(name)
+		}
+	}
+	depth := opts.Depth
+	if depth <= 0 {
+		depth = 4
+	}
+	src, err := cwav.Decode(r)
+	if err != nil {
+		return err
+	}
+	pcm := src.Data.PCMReader()
+	if pcm == nil {
+		return errors.New("trim: nil PCM reader")
+	}
+	total := src.NumFrames()
+	order := make([]int, len(cuts))
+	ranges := make([][2]int64, len(cuts))
+	for i, c := range cuts {
+		start, end, err := frameRange(c.Start, c.End, total)
+		if err != nil {
+			return fmt.Errorf("cut %d: %w", i, err)
+		}
+		ranges[i] = [2]int64{start, end}
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return ranges[order[i]][0] < ranges[order[j]][0]
+	})
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		werr    error
+		active  []*cutWriter
+		next    int // index into order of the next cut to start
+		pos     int64
+		blkSize = int64(src.Fmt.BlockAlign)
+	)
+	failed := func() error {
+		mu.Lock()
+		defer mu.Unlock()
+		return werr
+	}
+	start := func(i int) {
+		cw := &cutWriter{
+			index: i,
+			name:  cuts[i].Name,
+			start: ranges[i][0],
+			end:   ranges[i][1],
+			ch:    make(chan []byte, depth),
+		}
+		active = append(active, cw)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := cw.run(src, create); err != nil {
+				mu.Lock()
+				if werr == nil {
+					werr = err
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	finish := func() error {
+		for _, cw := range active {
+			close(cw.ch)
+		}
+		wg.Wait()
+		return failed()
+	}
+
+	for next < len(order) || len(active) > 0 {
+		if err := failed(); err != nil {
+			break
+		}
+		if len(active) == 0 {
+			pos = ranges[order[next]][0]
+			if _, err := pcm.Seek(pos*blkSize, io.SeekStart); err != nil {
+				finish()
+				return err
+			}
+		}
+		for next < len(order) && ranges[order[next]][0] == pos {
+			start(order[next])
+			next++
+		}
+		// stop the block at the next start or end so that every active
+		// cut takes all of it
+		end := pos + copyFrames
+		if next < len(order) {
+			end = min(end, ranges[order[next]][0])
+		}
+		for _, cw := range active {
+			end = min(end, cw.end)
+		}
+		p := make([]byte, (end-pos)*blkSize)
+		if _, err := io.ReadFull(pcm, p); err != nil {
+			finish()
+			return err
+		}
+		pos = end
+		n := 0
+		for _, cw := range active {
+			cw.ch <- p
+			if cw.end == pos {
+				close(cw.ch)
+				continue
+			}
+			active[n] = cw
+			n++
+		}
+		active = active[:n]
+	}
+	return finish()
+}
+
+// cutWriter writes the blocks of a single cut.
+type cutWriter struct {
+	index      int
+	name       string
+	start, end int64
+	ch         chan []byte
+}
+
+func (cw *cutWriter) run(src *cwav.WavFile, create func(string) (io.WriteSeeker, error)) (err error) {
+	defer func() {
+		// keep draining so that the reader never blocks on a failed cut
+		for range cw.ch {
+		}
+		if err != nil {
+			err = fmt.Errorf("trim: cut %d: %w", cw.index, err)
+		}
+	}()
+	w, err := create(cw.name)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if c, ok := w.(io.Closer); ok {
+			if cerr := c.Close(); err == nil {
+				err = cerr
+			}
+		}
+	}()
+	wf, err := cwav.CreateFmt(w, src.Fmt)
+	if err != nil {
+		return err
+	}
+	dst := wf.Data.PCMWriter()
+	for p := range cw.ch {
+		if _, err := dst.Write(p); err != nil {
+			return err
+		}
+	}
+	setMetadata(wf, src, cw.start, cw.end, &TrimOptions{})
+	_, err = wf.Encode(w)
+	return err
+}
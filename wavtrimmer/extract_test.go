@@ -0,0 +1,99 @@
+package wavtrimmer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// readLog records the ranges read from the underlying file.
+type readLog struct {
+	*os.File
+	reads [][2]int64
+}
+
+func (r *readLog) ReadAt(p []byte, off int64) (int, error) {
+	n, err := r.File.ReadAt(p, off)
+	r.reads = append(r.reads, [2]int64{off, off + int64(n)})
+	return n, err
+}
+
+func TestExtract(t *testing.T) {
+	const nchans = 3
+	src := frameSamples(20000, nchans)
+	in := &readLog{File: makeWav(t, 8000, nchans, 16, src)}
+	dir := t.TempDir()
+	cuts := []Cut{
+		{5000, 9000, "a"},
+		{100, 200, "b"},
+		{6000, 7000, "c"},  // inside a
+		{8500, 12000, "d"}, // overlaps a
+		{15000, -1, "e"},
+		{-1, 50, "f"},
+		{100, 200, "g"}, // same as b
+	}
+	err := Extract(in, cuts, &ExtractOptions{
+		Create: func(name string) (io.WriteSeeker, error) {
+			return os.Create(filepath.Join(dir, name))
+		},
+		Depth: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range cuts {
+		f, err := os.Open(filepath.Join(dir, c.Name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		_, got := readWav(t, f)
+		start, end, _ := frameRange(c.Start, c.End, 20000)
+		if !equalSamples(got, src[start*nchans:end*nchans]) {
+			t.Errorf("cut %s: got %d frames, want [%d, %d)", c.Name, len(got)/nchans, start, end)
+		}
+	}
+
+	// the PCM data must be read in order, each byte at most once
+	if len(in.reads) == 0 {
+		t.Fatal("no reads logged")
+	}
+	var last int64
+	for _, r := range in.reads {
+		if r[0] < 44 {
+			continue // header
+		}
+		if r[0] < last {
+			t.Fatalf("read %v after reading up to %d", r, last)
+		}
+		last = r[1]
+	}
+}
+
+func TestExtractErrors(t *testing.T) {
+	in := makeWav(t, 8000, 1, 16, frameSamples(1000, 1))
+	if err := Extract(in, []Cut{{0, 10, "x"}, {10, 1001, "y"}}, nil); err == nil {
+		t.Error("Extract() with a cut past the end succeeded")
+	}
+
+	in.Seek(0, io.SeekStart)
+	var cuts []Cut
+	for i := 0; i < 20; i++ {
+		cuts = append(cuts, Cut{int64(i * 10), int64(i*10 + 500), fmt.Sprint(i)})
+	}
+	errFull := errors.New("disk full")
+	err := Extract(in, cuts, &ExtractOptions{
+		Create: func(name string) (io.WriteSeeker, error) {
+			if name == "7" {
+				return nil, errFull
+			}
+			return tempFile(t), nil
+		},
+	})
+	if !errors.Is(err, errFull) {
+		t.Errorf("Extract() = %v, want %v", err, errFull)
+	}
+}
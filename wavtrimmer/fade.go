@@ -0,0 +1,149 @@
+package wavtrimmer
+
+import (
+	"io"
+	"math"
+
+	cwav "github.com/cakturk/pkg/wav"
+)
+
+// Curve is the shape of a fade.
+type Curve int
+
+const (
+	Linear      Curve = iota
+	EqualPower        // quarter sine, keeps the power constant in crossfades
+	Logarithmic       // linear in dB, from -60 dB up
+)
+
+// gain returns the gain of the curve at x in [0, 1]; 0 is silent, 1 is
+// unchanged.
+func (c Curve) gain(x float64) float64 {
+	switch c {
+	case EqualPower:
+		return math.Sin(x * math.Pi / 2)
+	case Logarithmic:
+		if x <= 0 {
+			return 0
+		}
+		return math.Pow(10, -3*(1-x))
+	default:
+		return x
+	}
+}
+
+// writeFaded writes the frames [start, end) of src into dst, fading in over
+// the first fadeIn frames and out over the last fadeOut frames.
+func writeFaded(dst, src *cwav.WavFile, start, end, fadeIn, fadeOut int64, c Curve) error {
+	fr, err := src.RangeFrameReader(start, end)
+	if err != nil {
+		return err
+	}
+	fw, err := dst.FrameWriter()
+	if err != nil {
+		return err
+	}
+	nchans := int(src.Fmt.NumChans)
+	n := end - start
+	fadeIn, fadeOut = min(fadeIn, n), min(fadeOut, n)
+	p := make([]float64, copyFrames*nchans)
+	for pos := int64(0); ; {
+		got, err := fr.ReadFrames(p)
+		for i := 0; i < got; i++ {
+			f, g := pos+int64(i), 1.0
+			if f < fadeIn {
+				g *= c.gain(float64(f) / float64(fadeIn))
+			}
+			if f >= n-fadeOut {
+				g *= c.gain(float64(n-1-f) / float64(fadeOut))
+			}
+			for ch := 0; ch < nchans; ch++ {
+				p[i*nchans+ch] *= g
+			}
+		}
+		if _, werr := fw.WriteFrames(p[:got*nchans]); werr != nil {
+			return werr
+		}
+		pos += int64(got)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// snapRange moves start and end of the frame range [start, end) to the
+// nearest zero crossings within window frames. Boundaries at the start or
+// the end of src are not cuts and stay in place.
+func snapRange(src *cwav.WavFile, start, end, window int64) (int64, int64, error) {
+	s, e := start, end-1
+	var err error
+	if start > 0 {
+		if s, err = zeroCrossing(src, start, window); err != nil {
+			return 0, 0, err
+		}
+	}
+	// the last frame kept should be the one closest to zero
+	if end < src.NumFrames() {
+		if e, err = zeroCrossing(src, end-1, window); err != nil {
+			return 0, 0, err
+		}
+	}
+	if e+1 <= s {
+		return start, end, nil
+	}
+	return s, e + 1, nil
+}
+
+// zeroCrossing returns the frame nearest to frame at most window frames away
+// which is at a zero crossing, or frame itself if there is none. Channels are
+// summed. Of the two frames around a sign change, the one closer to zero
+// is taken.
+func zeroCrossing(src *cwav.WavFile, frame, window int64) (int64, error) {
+	lo := max(0, frame-window)
+	hi := min(src.NumFrames(), frame+window+1)
+	fr, err := src.RangeFrameReader(lo, hi)
+	if err != nil {
+		return 0, err
+	}
+	nchans := int(src.Fmt.NumChans)
+	p := make([]float64, int(hi-lo)*nchans)
+	if _, err := (&segment{fr: fr, nchans: nchans}).readFull(p); err != nil {
+		return 0, err
+	}
+	mono := make([]float64, hi-lo)
+	for i := range mono {
+		for ch := 0; ch < nchans; ch++ {
+			mono[i] += p[i*nchans+ch]
+		}
+	}
+	best, dist := frame, window+1
+	consider := func(i int) {
+		if d := abs(lo + int64(i) - frame); d < dist {
+			best, dist = lo+int64(i), d
+		}
+	}
+	for i, v := range mono {
+		if v == 0 {
+			consider(i)
+			continue
+		}
+		if i > 0 && mono[i-1] != 0 && (v < 0) != (mono[i-1] < 0) {
+			if math.Abs(v) < math.Abs(mono[i-1]) {
+				consider(i)
+			} else {
+				consider(i - 1)
+			}
+		}
+	}
+	return best, nil
+}
+
+func abs(x int64) int64 {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,100 @@
+package wavtrimmer
+
+import (
+	"io"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestTrimFade(t *testing.T) {
+	src := constSamples(200, 0.5) // 100 stereo frames
+	tests := []struct {
+		curve Curve
+		gain  func(x float64) float64
+	}{
+		{Linear, func(x float64) float64 { return x }},
+		{EqualPower, func(x float64) float64 { return math.Sin(x * math.Pi / 2) }},
+		{Logarithmic, func(x float64) float64 {
+			if x == 0 {
+				return 0
+			}
+			return math.Pow(10, -3*(1-x))
+		}},
+	}
+	for _, tt := range tests {
+		in := makeWav(t, 1000, 2, 32, src)
+		out := tempFile(t)
+		err := TrimWithOptions(in, 10*time.Millisecond, 90*time.Millisecond, out, &TrimOptions{
+			FadeIn:  10 * time.Millisecond,
+			FadeOut: 20 * time.Millisecond,
+			Curve:   tt.curve,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, got := readWav(t, out)
+		if len(got) != 160 {
+			t.Fatalf("got %d frames, want 80", len(got)/2)
+		}
+		for f := 0; f < 80; f++ {
+			want := 0.5
+			switch {
+			case f < 10:
+				want *= tt.gain(float64(f) / 10)
+			case f >= 60:
+				want *= tt.gain(float64(79-f) / 20)
+			}
+			for c := 0; c < 2; c++ {
+				if g := got[2*f+c]; math.Abs(g-want) > 1e-9 {
+					t.Errorf("curve %d, frame %d: got %v, want %v", tt.curve, f, g, want)
+				}
+			}
+		}
+		if got[0] != 0 || got[159] != 0 {
+			t.Errorf("curve %d: fades do not reach silence", tt.curve)
+		}
+	}
+}
+
+func TestTrimSnap(t *testing.T) {
+	// a 100 Hz sine at 8 kHz crosses zero every 40 frames, right before the
+	// multiples of 40. The output ends with the frame closest to zero.
+	src := make([]float64, 800)
+	for i := range src {
+		src[i] = 0.5 * math.Sin(2*math.Pi*100*float64(i)/8000+0.01)
+	}
+	in := makeWav(t, 8000, 1, 16, src)
+	tests := []struct {
+		start, end  int64
+		snap        time.Duration
+		first, last int64
+	}{
+		{45, 395, time.Millisecond, 40, 401}, // 1ms = 8 frames
+		{35, 405, time.Millisecond, 40, 401},
+		{50, 390, time.Millisecond, 50, 390}, // too far from a crossing
+		{2, 799, 5 * time.Millisecond, 40, 761},
+		// the ends of the file are not cuts
+		{-1, -1, 5 * time.Millisecond, 0, 800},
+		{0, 395, 5 * time.Millisecond, 0, 401},
+		{45, 800, time.Millisecond, 40, 800},
+	}
+	for _, tt := range tests {
+		in.Seek(0, io.SeekStart)
+		out := tempFile(t)
+		err := TrimFramesWithOptions(in, tt.start, tt.end, out, &TrimOptions{Snap: tt.snap})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, got := readWav(t, out)
+		in.Seek(0, io.SeekStart)
+		ref := tempFile(t)
+		if err := TrimFrames(in, tt.first, tt.last, ref); err != nil {
+			t.Fatal(err)
+		}
+		_, want := readWav(t, ref)
+		if !equalSamples(got, want) {
+			t.Errorf("snap [%d, %d): got %d frames, want [%d, %d)", tt.start, tt.end, len(got), tt.first, tt.last)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package wavtrimmer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	cwav "github.com/cakturk/pkg/wav"
+)
+
+// File is a wav file which can be trimmed in place, e.g. an *os.File opened
+// for reading and writing.
+type File interface {
+	io.ReadSeeker
+	io.ReaderAt
+	io.WriterAt
+	Truncate(size int64) error
+}
+
+// InPlaceOptions controls TrimInPlace.
+type InPlaceOptions struct {
+	// MoveHead allows trims which do not start at the first frame. The
+	// kept audio is then moved to the start of the data chunk in blocks of
+	// BlockSize bytes, which takes time but no extra disk space. An
+	// interrupted move leaves the file corrupt.
+	MoveHead bool
+	// BlockSize defaults to 1 MiB.
+	BlockSize int
+}
+
+// TrimInPlace trims the wav file f to the time interval [start, end), with
+// the same boundaries as Trim, without copying it. The data chunk and RIFF
+// header sizes and the sample count of a fact chunk are rewritten, the
+// chunks following the audio are moved right after the kept part and the
+// file is truncated. Cue points are moved along with the audio and dropped
+// if outside of it.
+func TrimInPlace(f File, start, end time.Duration, opts *InPlaceOptions) error {
+	src, err := cwav.Decode(f)
+	if err != nil {
+		return err
+	}
+	first, last, err := durationRange(start, end, src)
+	if err != nil {
+		return err
+	}
+	return trimInPlace(f, src, first, last, opts)
+}
+
+// TrimFramesInPlace is like TrimInPlace for the frames [start, end), as
+// TrimFrames.
+func TrimFramesInPlace(f File, start, end int64, opts *InPlaceOptions) error {
+	src, err := cwav.Decode(f)
+	if err != nil {
+		return err
+	}
+	return trimInPlace(f, src, start, end, opts)
+}
+
+func trimInPlace(f File, src *cwav.WavFile, start, end int64, opts *InPlaceOptions) error {
+	if opts == nil {
+		opts = &InPlaceOptions{}
+	}
+	first, last, err := frameRange(start, end, src.NumFrames())
+	if err != nil {
+		return err
+	}
+	if first > 0 && !opts.MoveHead {
+		return fmt.Errorf("trim: start frame: %d is not 0 and moving the audio is not allowed", first)
+	}
+	blockAlign := int64(src.Fmt.BlockAlign)
+	dataOff := src.Data.Offset()
+	size := int64(src.Data.SubChunkSize)
+
+	fileEnd, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	tail := make([]byte, max(0, fileEnd-(dataOff+size)))
+	if _, err := f.ReadAt(tail, dataOff+size); err != nil && err != io.EOF {
+		return err
+	}
+	// skip the pad byte of an odd sized data chunk, if written. Chunk IDs
+	// never start with a NUL.
+	if size&1 != 0 && len(tail) > 0 && tail[0] == 0 {
+		tail = tail[1:]
+	}
+	if tail, err = trailingChunks(tail, src.Cue, first, last); err != nil {
+		return err
+	}
+
+	if first > 0 {
+		if err := moveForward(f, dataOff+first*blockAlign, dataOff, (last-first)*blockAlign, opts.BlockSize); err != nil {
+			return err
+		}
+	}
+	size = (last - first) * blockAlign
+	if size&1 != 0 {
+		tail = append([]byte{0}, tail...)
+	}
+	off := dataOff + size
+	if _, err := f.WriteAt(tail, off); err != nil {
+		return err
+	}
+	fileEnd = off + int64(len(tail))
+	var p [4]byte
+	binary.LittleEndian.PutUint32(p[:], uint32(size))
+	if _, err := f.WriteAt(p[:], dataOff-4); err != nil {
+		return err
+	}
+	binary.LittleEndian.PutUint32(p[:], uint32(fileEnd-8))
+	if _, err := f.WriteAt(p[:], 4); err != nil {
+		return err
+	}
+	if err := updateFact(f, dataOff-8, last-first); err != nil {
+		return err
+	}
+	return f.Truncate(fileEnd)
+}
+
+// updateFact sets the sample count of the fact chunk found among the chunks
+// between the RIFF header and dataHdr, the offset of the data chunk header,
+// to frames.
+func updateFact(f File, dataHdr, frames int64) error {
+	var hdr [8]byte
+	for off := int64(cwav.RIFFHdrSize); off+8 <= dataHdr; {
+		if _, err := f.ReadAt(hdr[:], off); err != nil {
+			return err
+		}
+		size := int64(binary.LittleEndian.Uint32(hdr[4:]))
+		if [4]byte(hdr[:4]) == cwav.FACT && size >= 4 {
+			var p [4]byte
+			binary.LittleEndian.PutUint32(p[:], uint32(frames))
+			_, err := f.WriteAt(p[:], off+8)
+			return err
+		}
+		off += 8 + size + size&1
+	}
+	return nil
+}
+
+// trailingChunks returns the chunks following the audio, found in b, with
+// the cue points of cue moved to the frames [start, end). Other chunks are
+// kept as they are.
+func trailingChunks(b []byte, cue *cwav.CueChunk, start, end int64) ([]byte, error) {
+	var out bytes.Buffer
+	for len(b) >= 8 {
+		n := 8 + int64(binary.LittleEndian.Uint32(b[4:8]))
+		if n > int64(len(b)) {
+			break
+		}
+		// a missing pad byte at the end of the file is tolerated
+		n = min(n+n&1, int64(len(b)))
+		if [4]byte(b[:4]) != cwav.CUE {
+			out.Write(b[:n])
+		} else if cue != nil {
+			if c := shiftCues(cue, [][2]int64{{start, end}}, []int64{0}); c != nil {
+				if err := c.Pack(&out); err != nil {
+					return nil, err
+				}
+			}
+		}
+		b = b[n:]
+	}
+	if len(b) != 0 {
+		return nil, errors.New("trim: truncated chunk after the audio")
+	}
+	return out.Bytes(), nil
+}
+
+// moveForward copies n bytes of f from src to the lower offset dst, one
+// block at a time.
+func moveForward(f File, src, dst, n int64, blockSize int) error {
+	if blockSize <= 0 {
+		blockSize = 1 << 20
+	}
+	buf := make([]byte, min(int64(blockSize), n))
+	for done := int64(0); done < n; {
+		p := buf[:min(int64(len(buf)), n-done)]
+		if _, err := f.ReadAt(p, src+done); err != nil {
+			return err
+		}
+		if _, err := f.WriteAt(p, dst+done); err != nil {
+			return err
+		}
+		done += int64(len(p))
+	}
+	return nil
+}
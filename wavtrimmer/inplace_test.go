@@ -0,0 +1,156 @@
+package wavtrimmer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"testing"
+
+	cwav "github.com/cakturk/pkg/wav"
+)
+
+func TestTrimInPlace(t *testing.T) {
+	tests := []struct {
+		start, end int64
+		opts       *InPlaceOptions
+		cues       string
+	}{
+		{-1, 200, nil, "[50 150]"},
+		{0, 120, nil, "[50]"},
+		{100, -1, &InPlaceOptions{MoveHead: true}, "[50]"},
+		{33, 251, &InPlaceOptions{MoveHead: true, BlockSize: 7}, "[17 117]"},
+		{160, 170, &InPlaceOptions{MoveHead: true}, "[]"},
+	}
+	for _, tt := range tests {
+		in := taggedWav(t)
+		_, samples := readWav(t, in)
+		in.Seek(0, io.SeekStart)
+		if err := TrimFramesInPlace(in, tt.start, tt.end, tt.opts); err != nil {
+			t.Fatal(err)
+		}
+		wf, got := readWav(t, in)
+		want := samples[max(tt.start, 0):]
+		if tt.end != -1 {
+			want = samples[max(tt.start, 0):tt.end]
+		}
+		if !equalSamples(got, want) {
+			t.Errorf("[%d, %d): got %d frames differing from the source", tt.start, tt.end, len(got))
+		}
+		if wf.List == nil || wf.List.InfoChunk(cwav.INAM) != "interview" {
+			t.Errorf("[%d, %d): LIST not kept: %+v", tt.start, tt.end, wf.List)
+		}
+		if got := fmt.Sprint(cueOffsets(wf)); got != tt.cues {
+			t.Errorf("[%d, %d): cues = %s, want %s", tt.start, tt.end, got, tt.cues)
+		}
+		fi, err := in.Stat()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if size := int64(wf.Hdr.ChunkSize) + 8; size != fi.Size() {
+			t.Errorf("[%d, %d): RIFF size %d, file size %d", tt.start, tt.end, size, fi.Size())
+		}
+	}
+}
+
+func TestTrimInPlaceOddSize(t *testing.T) {
+	// 8-bit mono: an odd number of frames leaves the data chunk odd sized
+	samples := frameSamples(101, 1)
+	in := makeWav(t, 1000, 1, 8, samples)
+	wf, _ := readWav(t, in)
+	wf.List = &cwav.ListChunk{
+		SubChunkID: cwav.LIST,
+		TypeID:     cwav.INFO,
+		SubChunks:  []cwav.InfoChunk{{ID: cwav.INAM, Text: []byte("odd")}},
+	}
+	if _, err := wf.Encode(in); err != nil {
+		t.Fatal(err)
+	}
+	for _, end := range []int64{100, 51} {
+		in.Seek(0, io.SeekStart)
+		if err := TrimFramesInPlace(in, 0, end, nil); err != nil {
+			t.Fatal(err)
+		}
+		wf, got := readWav(t, in)
+		if !equalSamples(got, samples[:end]) {
+			t.Errorf("end %d: got %d frames differing from the source", end, len(got))
+		}
+		if wf.List == nil || wf.List.InfoChunk(cwav.INAM) != "odd" {
+			t.Errorf("end %d: LIST not kept: %+v", end, wf.List)
+		}
+		b, err := os.ReadFile(in.Name())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if size := int64(wf.Hdr.ChunkSize) + 8; size != int64(len(b)) {
+			t.Errorf("end %d: RIFF size %d, file size %d", end, size, len(b))
+		}
+		if pad := wf.Data.Offset() + end; end&1 != 0 && b[pad] != 0 {
+			t.Errorf("end %d: no pad byte after the data chunk", end)
+		}
+	}
+}
+
+func TestTrimInPlaceFact(t *testing.T) {
+	b, err := os.ReadFile(taggedWav(t).Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// insert a fact chunk right before the data chunk
+	i := bytes.Index(b, cwav.DATA[:])
+	fact := []byte{'f', 'a', 'c', 't', 4, 0, 0, 0, 44, 1, 0, 0} // 300 frames
+	b = append(b[:i:i], append(fact, b[i:]...)...)
+	binary.LittleEndian.PutUint32(b[4:8], uint32(len(b)-8))
+	in := tempFile(t)
+	if _, err := in.Write(b); err != nil {
+		t.Fatal(err)
+	}
+	in.Seek(0, io.SeekStart)
+	if err := TrimFramesInPlace(in, 100, 220, &InPlaceOptions{MoveHead: true}); err != nil {
+		t.Fatal(err)
+	}
+	if _, got := readWav(t, in); len(got) != 120 {
+		t.Errorf("got %d frames, want 120", len(got))
+	}
+	if _, err := in.ReadAt(fact, int64(i)); err != nil {
+		t.Fatal(err)
+	}
+	if n := binary.LittleEndian.Uint32(fact[8:]); n != 120 {
+		t.Errorf("fact sample count %d, want 120", n)
+	}
+}
+
+func TestTrimInPlaceErrors(t *testing.T) {
+	tests := []struct {
+		start, end int64
+		opts       *InPlaceOptions
+	}{
+		{10, 100, nil},
+		{0, 400, nil},
+		{200, 100, &InPlaceOptions{MoveHead: true}},
+	}
+	for _, tt := range tests {
+		in := taggedWav(t)
+		if err := TrimFramesInPlace(in, tt.start, tt.end, tt.opts); err == nil {
+			t.Errorf("[%d, %d): got no error", tt.start, tt.end)
+		}
+		// the file is left alone
+		if _, got := readWav(t, in); len(got) != 300 {
+			t.Errorf("[%d, %d): file changed to %d frames", tt.start, tt.end, len(got))
+		}
+	}
+
+	// a chunk after the audio running past the end of the file
+	in := taggedWav(t)
+	if _, err := in.Seek(0, io.SeekEnd); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := in.Write([]byte{'j', 'u', 'n', 'k', 100, 0, 0, 0, 1, 2, 3}); err != nil {
+		t.Fatal(err)
+	}
+	in.Seek(0, io.SeekStart)
+	if err := TrimFramesInPlace(in, 0, 100, nil); err == nil {
+		t.Error("truncated chunk: got no error")
+	}
+}
@@ -0,0 +1,101 @@
+package wavtrimmer
+
+import (
+	"fmt"
+
+	cwav "github.com/cakturk/pkg/wav"
+	"github.com/go-audio/wav"
+)
+
+// setMetadata sets the metadata of dst holding the frames [start, end) of
+// src according to opts.
+func setMetadata(dst, src *cwav.WavFile, start, end int64, opts *TrimOptions) {
+	if !opts.NoMetadata {
+		dst.List = copyList(src.List)
+		if src.Cue != nil {
+			dst.Cue = shiftCues(src.Cue, [][2]int64{{start, end}}, []int64{0})
+		}
+	}
+	if opts.Derived == nil {
+		return
+	}
+	for _, ic := range opts.Derived(src, start, end) {
+		setInfo(dst, ic)
+	}
+}
+
+// copyList returns a deep copy of l.
+func copyList(l *cwav.ListChunk) *cwav.ListChunk {
+	if l == nil {
+		return nil
+	}
+	c := *l
+	c.SubChunks = make([]cwav.InfoChunk, len(l.SubChunks))
+	for i, ic := range l.SubChunks {
+		ic.Text = append([]byte(nil), ic.Text...)
+		c.SubChunks[i] = ic
+	}
+	return &c
+}
+
+// setInfo sets the INFO field ic.ID of wf to ic.Text.
+func setInfo(wf *cwav.WavFile, ic cwav.InfoChunk) {
+	if wf.List == nil {
+		wf.List = &cwav.ListChunk{SubChunkID: cwav.LIST, TypeID: cwav.INFO}
+	}
+	for i := range wf.List.SubChunks {
+		if wf.List.SubChunks[i].ID == ic.ID {
+			wf.List.SubChunks[i].Text = ic.Text
+			return
+		}
+	}
+	wf.List.SubChunks = append(wf.List.SubChunks, ic)
+}
+
+// TrimmedFrom returns a TrimOptions.Derived function which records where
+// the output was cut from in its comment (ICMT), after the comment of the
+// source if any, e.g. "trimmed from talk.wav at 1.5s (frame 12000)".
+func TrimmedFrom(name string) func(src *cwav.WavFile, start, end int64) []cwav.InfoChunk {
+	return func(src *cwav.WavFile, start, end int64) []cwav.InfoChunk {
+		text := fmt.Sprintf("trimmed from %s at %s (frame %d)",
+			name, src.FrameDuration(start), start)
+		if src.List != nil {
+			if c := src.List.InfoChunk(cwav.ICMT); c != "" {
+				text = c + "; " + text
+			}
+		}
+		return []cwav.InfoChunk{{ID: cwav.ICMT, Text: []byte(text)}}
+	}
+}
+
+// goaudioMetadata converts the INFO fields of l that go-audio knows about.
+func goaudioMetadata(l *cwav.ListChunk) *wav.Metadata {
+	if l == nil {
+		return nil
+	}
+	m := &wav.Metadata{}
+	fields := map[[4]byte]*string{
+		cwav.IART: &m.Artist,
+		cwav.ICMT: &m.Comments,
+		cwav.ICOP: &m.Copyright,
+		cwav.ICRD: &m.CreationDate,
+		cwav.IENG: &m.Engineer,
+		cwav.ITCH: &m.Technician,
+		cwav.IGNR: &m.Genre,
+		cwav.IKEY: &m.Keywords,
+		cwav.IMED: &m.Medium,
+		cwav.INAM: &m.Title,
+		cwav.IPRD: &m.Product,
+		cwav.ISBJ: &m.Subject,
+		cwav.ISFT: &m.Software,
+		cwav.ISRC: &m.Source,
+		cwav.IARL: &m.Location,
+		cwav.ITRK: &m.TrackNbr,
+	}
+	for _, ic := range l.SubChunks {
+		if f, ok := fields[ic.ID]; ok {
+			*f = string(ic.Text)
+		}
+	}
+	return m
+}
@@ -0,0 +1,162 @@
+package wavtrimmer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	cwav "github.com/cakturk/pkg/wav"
+)
+
+// taggedWav returns a mono 1 kHz file of 300 frames with a title, a comment
+// and cue points at frames 50 and 150.
+func taggedWav(t *testing.T) *os.File {
+	t.Helper()
+	f := tempFile(t)
+	wf, err := cwav.Create(f, 1000, 1, 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fw, _ := wf.FrameWriter()
+	if _, err := fw.WriteFrames(frameSamples(300, 1)); err != nil {
+		t.Fatal(err)
+	}
+	wf.List = &cwav.ListChunk{
+		SubChunkID: cwav.LIST,
+		TypeID:     cwav.INFO,
+		SubChunks: []cwav.InfoChunk{
+			{ID: cwav.INAM, Text: []byte("interview")},
+			{ID: cwav.ICMT, Text: []byte("take 2")},
+		},
+	}
+	wf.Cue = cwav.NewCueChunk(50, 150)
+	if _, err := wf.Encode(f); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func cueOffsets(wf *cwav.WavFile) []uint32 {
+	if wf.Cue == nil {
+		return nil
+	}
+	var offs []uint32
+	for _, p := range wf.Cue.Points {
+		offs = append(offs, p.SampleOffset)
+	}
+	return offs
+}
+
+func TestTrimMetadata(t *testing.T) {
+	in := taggedWav(t)
+	out := tempFile(t)
+	if err := Trim2(in, 100*time.Millisecond, 200*time.Millisecond, out); err != nil {
+		t.Fatal(err)
+	}
+	wf, _ := readWav(t, out)
+	if wf.List == nil || wf.List.InfoChunk(cwav.INAM) != "interview" || wf.List.InfoChunk(cwav.ICMT) != "take 2" {
+		t.Errorf("LIST not copied: %+v", wf.List)
+	}
+	if got := fmt.Sprint(cueOffsets(wf)); got != "[50]" {
+		t.Errorf("cues = %s, want [50]", got)
+	}
+
+	in.Seek(0, io.SeekStart)
+	out = tempFile(t)
+	err := TrimFramesWithOptions(in, 100, 200, out, &TrimOptions{NoMetadata: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wf, _ := readWav(t, out); wf.List != nil || wf.Cue != nil {
+		t.Errorf("metadata not dropped: %+v, %+v", wf.List, wf.Cue)
+	}
+
+	in.Seek(0, io.SeekStart)
+	out = tempFile(t)
+	err = TrimFramesWithOptions(in, 120, -1, out, &TrimOptions{Derived: TrimmedFrom("talk.wav")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wf, _ = readWav(t, out)
+	if got, want := wf.List.InfoChunk(cwav.ICMT), "take 2; trimmed from talk.wav at 120ms (frame 120)"; got != want {
+		t.Errorf("ICMT = %q, want %q", got, want)
+	}
+	if got := wf.List.InfoChunk(cwav.INAM); got != "interview" {
+		t.Errorf("INAM = %q", got)
+	}
+}
+
+func TestTrimMetadataBeforeData(t *testing.T) {
+	b, err := os.ReadFile(taggedWav(t).Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// move the LIST chunk in front of the audio, as ffmpeg writes it
+	data := bytes.Index(b, cwav.DATA[:])
+	list := data + bytes.Index(b[data:], cwav.LIST[:])
+	end := list + 8 + int(binary.LittleEndian.Uint32(b[list+4:]))
+	var moved []byte
+	moved = append(moved, b[:data]...)
+	moved = append(moved, b[list:end]...)
+	moved = append(moved, b[data:list]...)
+	moved = append(moved, b[end:]...)
+	in := tempFile(t)
+	if _, err := in.Write(moved); err != nil {
+		t.Fatal(err)
+	}
+	in.Seek(0, io.SeekStart)
+	out := tempFile(t)
+	if err := Trim2(in, 100*time.Millisecond, 200*time.Millisecond, out); err != nil {
+		t.Fatal(err)
+	}
+	wf, got := readWav(t, out)
+	if len(got) != 100 {
+		t.Errorf("got %d frames, want 100", len(got))
+	}
+	if wf.List == nil || wf.List.InfoChunk(cwav.INAM) != "interview" {
+		t.Errorf("LIST not copied: %+v", wf.List)
+	}
+}
+
+func TestTrimMetadataGoAudio(t *testing.T) {
+	in := taggedWav(t)
+	out := tempFile(t)
+	if err := Trim(in, 0, 100*time.Millisecond, out); err != nil {
+		t.Fatal(err)
+	}
+	wf, _ := readWav(t, out)
+	if wf.List == nil || wf.List.InfoChunk(cwav.INAM) != "interview" {
+		t.Errorf("LIST not copied: %+v", wf.List)
+	}
+}
+
+func TestExtractMetadata(t *testing.T) {
+	in := taggedWav(t)
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a.wav"), filepath.Join(dir, "b.wav")
+	if err := Extract(in, []Cut{{0, 100, a}, {140, 300, b}}, nil); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{a: "[50]", b: "[10]"} {
+		f, err := os.Open(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		wf, _ := readWav(t, f)
+		if got := fmt.Sprint(cueOffsets(wf)); got != want {
+			t.Errorf("%s: cues = %s, want %s", name, got, want)
+		}
+		if wf.List.InfoChunk(cwav.INAM) != "interview" {
+			t.Errorf("%s: LIST not copied", name)
+		}
+	}
+}
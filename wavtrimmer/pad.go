@@ -0,0 +1,90 @@
+package wavtrimmer
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	cwav "github.com/cakturk/pkg/wav"
+)
+
+// PadMode tells where the silence filling the part of a range past the end
+// of the source goes.
+type PadMode int
+
+const (
+	// NoPad rejects ranges past the end of the source.
+	NoPad PadMode = iota
+	// PadEnd appends the silence after the audio.
+	PadEnd
+	// PadStart inserts the silence before the audio.
+	PadStart
+)
+
+// Padding is the number of frames of silence added before and after the
+// audio by a padded trim.
+type Padding struct {
+	Start int64 `json:"start"`
+	End   int64 `json:"end"`
+}
+
+// Frames returns the total number of frames of silence.
+func (p Padding) Frames() int64 {
+	return p.Start + p.End
+}
+
+// TrimPadded is like TrimWithOptions but reports the silence added when
+// opts.Pad is set. The output then always holds end-start of audio: the part
+// of the range within the source, and silence for the rest. The silence is
+// digital silence in the sample format of the source, e.g. 0x80 for 8-bit
+// PCM.
+func TrimPadded(r io.ReadSeeker, start, end time.Duration, w io.WriteSeeker, opts *TrimOptions) (Padding, error) {
+	wavSrc, err := cwav.Decode(r)
+	if err != nil {
+		return Padding{}, err
+	}
+	first, last, err := durationRange(start, end, wavSrc)
+	if err != nil {
+		return Padding{}, err
+	}
+	return trimDecoded(wavSrc, first, last, w, opts)
+}
+
+// TrimFramesPadded is like TrimPadded for the frames [start, end).
+func TrimFramesPadded(r io.ReadSeeker, start, end int64, w io.WriteSeeker, opts *TrimOptions) (Padding, error) {
+	wavSrc, err := cwav.Decode(r)
+	if err != nil {
+		return Padding{}, err
+	}
+	return trimDecoded(wavSrc, start, end, w, opts)
+}
+
+// padRange is like frameRange but lets the range run past the end of a file
+// of total frames. It returns the part of the range within the file and the
+// padding making up for the rest.
+func padRange(start, end, total int64, mode PadMode) (int64, int64, Padding, error) {
+	if mode != PadEnd && mode != PadStart {
+		return 0, 0, Padding{}, fmt.Errorf("trim: unknown pad mode: %d", mode)
+	}
+	if start == -1 {
+		start = 0
+	}
+	if end == -1 {
+		end = total
+	}
+	if start < 0 || end < 0 {
+		return 0, 0, Padding{}, fmt.Errorf("trim: negative start frame: %d or end frame: %d", start, end)
+	}
+	if start >= end {
+		return 0, 0, Padding{}, fmt.Errorf("trim: start frame: %d not earlier than end frame: %d", start, end)
+	}
+	first, last := min(start, total), min(end, total)
+	var pad Padding
+	missing := end - start - (last - first)
+	if mode == PadStart {
+		pad.Start = missing
+	} else {
+		pad.End = missing
+	}
+	return first, last, pad, nil
+}
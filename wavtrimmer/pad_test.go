@@ -0,0 +1,102 @@
+package wavtrimmer
+
+import (
+	"fmt"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestTrimPadded(t *testing.T) {
+	samples := frameSamples(100, 2)
+	tests := []struct {
+		start, end int64
+		mode       PadMode
+		first      int64 // first source frame in the output
+		pad        Padding
+	}{
+		{50, 150, PadEnd, 50, Padding{End: 50}},
+		{50, 150, PadStart, 50, Padding{Start: 50}},
+		{-1, 130, PadEnd, 0, Padding{End: 30}},
+		{20, 60, PadEnd, 20, Padding{}},
+		{120, 140, PadStart, 100, Padding{Start: 20}},
+	}
+	for _, tt := range tests {
+		in := makeWav(t, 1000, 2, 8, samples)
+		out := tempFile(t)
+		pad, err := TrimFramesPadded(in, tt.start, tt.end, out, &TrimOptions{Pad: tt.mode})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pad != tt.pad {
+			t.Errorf("[%d, %d): padding = %+v, want %+v", tt.start, tt.end, pad, tt.pad)
+		}
+		wf, got := readWav(t, out)
+		length := tt.end - max(tt.start, 0)
+		want := make([]float64, length*2)
+		copy(want[pad.Start*2:], samples[tt.first*2:(tt.first+length-pad.Frames())*2])
+		if !equalSamples(got, want) {
+			t.Errorf("[%d, %d): got %v, want %v", tt.start, tt.end, got, want)
+		}
+		// 8-bit PCM silence is 0x80
+		if pad.Frames() > 0 {
+			pcm := make([]byte, wf.Data.SubChunkSize)
+			wf.Data.PCMReader().Seek(0, io.SeekStart)
+			if _, err := io.ReadFull(wf.Data.PCMReader(), pcm); err != nil {
+				t.Fatal(err)
+			}
+			i := len(pcm) - 1
+			if pad.Start > 0 {
+				i = 0
+			}
+			if pcm[i] != 0x80 {
+				t.Errorf("[%d, %d): silence is %#x, want 0x80", tt.start, tt.end, pcm[i])
+			}
+		}
+	}
+}
+
+func TestTrimPaddedDuration(t *testing.T) {
+	in := taggedWav(t) // 300ms, cues at 50 and 150
+	out := tempFile(t)
+	pad, err := TrimPadded(in, 100*time.Millisecond, 500*time.Millisecond, out, &TrimOptions{
+		Pad:     PadStart,
+		FadeOut: 10 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pad != (Padding{Start: 200}) {
+		t.Errorf("padding = %+v, want 200 frames at the start", pad)
+	}
+	wf, got := readWav(t, out)
+	if len(got) != 400 {
+		t.Errorf("got %d frames, want 400", len(got))
+	}
+	if got := fmt.Sprint(cueOffsets(wf)); got != "[250]" {
+		t.Errorf("cues = %s, want [250]", got)
+	}
+	// the fade ends the audio, not the silence
+	if got[len(got)-1] != 0 || got[len(got)-11] == 0 {
+		t.Errorf("fade out not applied to the end of the audio")
+	}
+}
+
+func TestTrimPaddedErrors(t *testing.T) {
+	tests := []struct {
+		start, end int64
+		mode       PadMode
+	}{
+		{50, 150, NoPad},
+		{50, 150, PadStart + 1},
+		{60, 50, PadEnd},
+		{-2, 50, PadEnd},
+		{150, -1, PadEnd},
+	}
+	for _, tt := range tests {
+		in := makeWav(t, 1000, 1, 16, frameSamples(100, 1))
+		if _, err := TrimFramesPadded(in, tt.start, tt.end, tempFile(t), &TrimOptions{Pad: tt.mode}); err == nil {
+			t.Errorf("%+v: got no error", tt)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package wavtrimmer
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	cwav "github.com/cakturk/pkg/wav"
+)
+
+// RangeExpr is a range of a wav file given relative to its start, its end
+// or its duration. See ParseRange.
+type RangeExpr struct {
+	expr       string
+	start, end pos
+	length     bool // end is relative to start
+}
+
+// pos is a position in a range expression.
+type pos struct {
+	set     bool
+	fromEnd bool
+	pct     bool
+	percent float64
+	d       time.Duration
+}
+
+// ParseRange parses a range expression of the form START:END or
+// START+LENGTH. START and END are positions, each one of
+//
+//   - a duration such as 90s or 1m2.5s, from the start of the file;
+//   - a duration preceded by '-' such as -30s, before the end of the file;
+//   - a percentage of the duration of the file such as 25% or 12.5%, or
+//     -25% before its end. Percentages are plain decimal numbers, without
+//     exponents, so that the first ':' or '+' always separates START;
+//   - empty, for the start of the file as START and its end as END.
+//
+// LENGTH is a duration or a percentage of the duration of the file. So
+// "-30s:" is the last 30 seconds, "-10s+5s" the 5 seconds starting 10
+// seconds before the end and "25%:75%" the middle half. Durations are
+// converted into frames rounding down, before being subtracted from the end
+// or added to START, so "-30s:" always holds 30 seconds of audio.
+func ParseRange(s string) (RangeExpr, error) {
+	e := RangeExpr{expr: s}
+	sep := strings.IndexAny(s, ":+")
+	if sep < 0 {
+		return e, fmt.Errorf("trim: invalid range %q: want START:END or START+LENGTH", s)
+	}
+	e.length = s[sep] == '+'
+	var err error
+	if e.start, err = parsePos(s[:sep], true); err != nil {
+		return e, fmt.Errorf("trim: invalid range %q: %w", s, err)
+	}
+	if e.end, err = parsePos(s[sep+1:], !e.length); err != nil {
+		return e, fmt.Errorf("trim: invalid range %q: %w", s, err)
+	}
+	if e.length && !e.end.set {
+		return e, fmt.Errorf("trim: invalid range %q: missing length", s)
+	}
+	return e, nil
+}
+
+// parsePos parses a position, which may be relative to the end of the file
+// if fromEnd is true.
+func parsePos(s string, fromEnd bool) (pos, error) {
+	var p pos
+	if s == "" {
+		return p, nil
+	}
+	p.set = true
+	v := s
+	if strings.HasPrefix(v, "-") {
+		if !fromEnd {
+			return p, fmt.Errorf("negative length %q", s)
+		}
+		p.fromEnd, v = true, v[1:]
+	}
+	if v == "" || v[0] == '-' || v[0] == '+' {
+		return p, fmt.Errorf("invalid position %q", s)
+	}
+	if num, ok := strings.CutSuffix(v, "%"); ok {
+		f, err := strconv.ParseFloat(num, 64)
+		if !isDecimal(num) || err != nil || math.IsNaN(f) || f < 0 || f > 100 {
+			return p, fmt.Errorf("invalid percentage %q: want 0%% - 100%%", s)
+		}
+		p.pct, p.percent = true, f
+		return p, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return p, fmt.Errorf("invalid duration %q", s)
+	}
+	p.d = d
+	return p, nil
+}
+
+// isDecimal reports whether s is a plain decimal number such as 12 or 12.5.
+func isDecimal(s string) bool {
+	digits, dots := 0, 0
+	for _, c := range s {
+		switch {
+		case c == '.':
+			dots++
+		case c >= '0' && c <= '9':
+			digits++
+		default:
+			return false
+		}
+	}
+	return digits > 0 && dots <= 1
+}
+
+// frames returns the number of frames p stands for in wf, before being
+// resolved against its start or end.
+func (p pos) frames(wf *cwav.WavFile) int64 {
+	if p.pct {
+		return int64(float64(wf.NumFrames()) * p.percent / 100)
+	}
+	return wf.DurationFrames(p.d)
+}
+
+// String returns the expression e was parsed from.
+func (e RangeExpr) String() string {
+	return e.expr
+}
+
+// Frames resolves e against the wav file wf into the frame range
+// [start, end). The range may end past the end of the file, when given by
+// an absolute END or a LENGTH, but never starts before its start.
+func (e RangeExpr) Frames(wf *cwav.WavFile) (int64, int64, error) {
+	total := wf.NumFrames()
+	start, end := int64(0), total
+	if e.start.set {
+		start = e.start.frames(wf)
+		if e.start.fromEnd {
+			start = total - start
+		}
+	}
+	if e.end.set {
+		end = e.end.frames(wf)
+		switch {
+		case e.length:
+			end += start
+		case e.end.fromEnd:
+			end = total - end
+		}
+	}
+	length := wf.FrameDuration(total)
+	if start < 0 {
+		return 0, 0, fmt.Errorf("trim: range %q starts before the start of the %s long file", e, length)
+	}
+	if end < 0 {
+		return 0, 0, fmt.Errorf("trim: range %q ends before the start of the %s long file", e, length)
+	}
+	if start >= end {
+		return 0, 0, fmt.Errorf("trim: range %q is empty: start %s not earlier than end %s",
+			e, wf.FrameDuration(start), wf.FrameDuration(end))
+	}
+	return start, end, nil
+}
+
+// TrimRange is like TrimPadded but takes the range to cut out of the source
+// as a range expression. Ranges past the end of the source are only allowed
+// with padding.
+func TrimRange(r io.ReadSeeker, e RangeExpr, w io.WriteSeeker, opts *TrimOptions) (Padding, error) {
+	wavSrc, err := cwav.Decode(r)
+	if err != nil {
+		return Padding{}, err
+	}
+	start, end, err := e.Frames(wavSrc)
+	if err != nil {
+		return Padding{}, err
+	}
+	if total := wavSrc.NumFrames(); (opts == nil || opts.Pad == NoPad) && end > total {
+		return Padding{}, fmt.Errorf("trim: range %q ends at %s, after the end of the %s long file",
+			e, wavSrc.FrameDuration(end), wavSrc.FrameDuration(total))
+	}
+	return trimDecoded(wavSrc, start, end, w, opts)
+}
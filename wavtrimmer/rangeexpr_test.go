@@ -0,0 +1,127 @@
+package wavtrimmer
+
+import (
+	"strings"
+	"testing"
+
+	cwav "github.com/cakturk/pkg/wav"
+)
+
+// tenSeconds is the header of a 10s long 16-bit mono file at 1kHz.
+var tenSeconds = &cwav.WavFile{
+	Fmt:  cwav.FmtChunk{SampleRate: 1000, BlockAlign: 2},
+	Data: cwav.DataChunk{SubChunkSize: 20000},
+}
+
+func TestRangeExprFrames(t *testing.T) {
+	tests := []struct {
+		expr       string
+		start, end int64
+	}{
+		{":", 0, 10000},
+		{"2s:", 2000, 10000},
+		{":1m0.5s", 0, 60500},
+		{"-3s:", 7000, 10000},
+		{"-3s:-1s", 7000, 9000},
+		{"-10s:", 0, 10000},
+		{"25%:75%", 2500, 7500},
+		{"12.5%:100%", 1250, 10000},
+		{"-10%:", 9000, 10000},
+		{"1.5s+500ms", 1500, 2000},
+		{"-2s+1s", 8000, 9000},
+		{"+20%", 0, 2000},
+		{"50%+20s", 5000, 25000},
+		{"1.0005s:1.0015s", 1000, 1001},
+	}
+	for _, tt := range tests {
+		e, err := ParseRange(tt.expr)
+		if err != nil {
+			t.Fatalf("%q: %v", tt.expr, err)
+		}
+		start, end, err := e.Frames(tenSeconds)
+		if err != nil {
+			t.Fatalf("%q: %v", tt.expr, err)
+		}
+		if start != tt.start || end != tt.end {
+			t.Errorf("%q: got [%d, %d), want [%d, %d)", tt.expr, start, end, tt.start, tt.end)
+		}
+	}
+}
+
+func TestRangeExprErrors(t *testing.T) {
+	tests := []struct {
+		expr, err string
+	}{
+		{"", "want START:END or START+LENGTH"},
+		{"10s", "want START:END or START+LENGTH"},
+		{"abc:", `invalid duration "abc"`},
+		{"--1s:", `invalid position "--1s"`},
+		{"-:", `invalid position "-"`},
+		{"101%:", `invalid percentage "101%"`},
+		{"x%:", `invalid percentage "x%"`},
+		{"NaN%:", `invalid percentage "NaN%"`},
+		{"-Inf%:", `invalid percentage "-Inf%"`},
+		{"1e1%:", `invalid percentage "1e1%"`},
+		{"1e+1%:50%", `invalid duration "1e"`},
+		{"1.2.3%:", `invalid percentage "1.2.3%"`},
+		{"1s+-2s", `negative length "-2s"`},
+		{"1s+", "missing length"},
+		{"1s:2s:3s", `invalid duration "2s:3s"`},
+	}
+	for _, tt := range tests {
+		_, err := ParseRange(tt.expr)
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%q: got error %v, want %q", tt.expr, err, tt.err)
+		}
+	}
+
+	resolve := []struct {
+		expr, err string
+	}{
+		{"-11s:", "starts before the start of the 10s long file"},
+		{":-11s", "ends before the start of the 10s long file"},
+		{"5s:5s", "is empty: start 5s not earlier than end 5s"},
+		{"-2s:-3s", "is empty: start 8s not earlier than end 7s"},
+		{"+0s", "is empty"},
+	}
+	for _, tt := range resolve {
+		e, err := ParseRange(tt.expr)
+		if err != nil {
+			t.Fatalf("%q: %v", tt.expr, err)
+		}
+		if _, _, err := e.Frames(tenSeconds); err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%q: got error %v, want %q", tt.expr, err, tt.err)
+		}
+	}
+}
+
+func TestTrimRange(t *testing.T) {
+	samples := frameSamples(1000, 1) // 1s
+	e, err := ParseRange("-300ms:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := tempFile(t)
+	if _, err := TrimRange(makeWav(t, 1000, 1, 16, samples), e, out, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, got := readWav(t, out); !equalSamples(got, samples[700:]) {
+		t.Errorf("got %d frames, want the last 300", len(got))
+	}
+
+	e, err = ParseRange("800ms+500ms")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = TrimRange(makeWav(t, 1000, 1, 16, samples), e, tempFile(t), nil)
+	if want := `trim: range "800ms+500ms" ends at 1.3s, after the end of the 1s long file`; err == nil || err.Error() != want {
+		t.Errorf("got error %v, want %q", err, want)
+	}
+	pad, err := TrimRange(makeWav(t, 1000, 1, 16, samples), e, tempFile(t), &TrimOptions{Pad: PadEnd})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pad != (Padding{End: 300}) {
+		t.Errorf("padding = %+v, want 300 frames at the end", pad)
+	}
+}
@@ -0,0 +1,158 @@
+package wavtrimmer
+
+import (
+	"errors"
+	"io"
+	"math"
+	"time"
+
+	cwav "github.com/cakturk/pkg/wav"
+)
+
+// Range is the half-open interval of frames [StartFrame, EndFrame) along
+// with its position in time.
+type Range struct {
+	Start      time.Duration `json:"start"`
+	End        time.Duration `json:"end"`
+	StartFrame int64         `json:"start_frame"`
+	EndFrame   int64         `json:"end_frame"`
+}
+
+func newRange(wf *cwav.WavFile, start, end int64) Range {
+	return Range{
+		Start:      wf.FrameDuration(start),
+		End:        wf.FrameDuration(end),
+		StartFrame: start,
+		EndFrame:   end,
+	}
+}
+
+// Len returns the length of the range in frames.
+func (r Range) Len() int64 {
+	return r.EndFrame - r.StartFrame
+}
+
+// SilenceOptions controls silence detection.
+type SilenceOptions struct {
+	// Threshold is the level, in dBFS, below which audio is considered
+	// silent. Defaults to -50 dBFS if zero.
+	Threshold float64
+	// Hysteresis is how far, in dB, the level has to rise above
+	// Threshold to end a silent region.
+	Hysteresis float64
+	// MinDuration is the length of the shortest silence reported.
+	MinDuration time.Duration
+	// Window is the length of the blocks whose RMS level is compared
+	// against the threshold. Defaults to 10ms if zero.
+	Window time.Duration
+}
+
+const defaultThreshold = -50
+
+// DetectSilence returns the silent regions of wf. A block of frames is
+// silent if the RMS level of each of its channels is below the threshold, so
+// the boundaries of the regions are aligned to the detection window.
+func DetectSilence(wf *cwav.WavFile, opts *SilenceOptions) ([]Range, error) {
+	if opts == nil {
+		opts = &SilenceOptions{}
+	}
+	enter := opts.Threshold
+	if enter == 0 {
+		enter = defaultThreshold
+	}
+	leave := enter + math.Abs(opts.Hysteresis)
+	window := opts.Window
+	if window <= 0 {
+		window = 10 * time.Millisecond
+	}
+	block := max(1, wf.DurationFrames(window))
+	minLen := wf.DurationFrames(opts.MinDuration)
+
+	pcm := wf.Data.PCMReader()
+	if pcm == nil {
+		return nil, errors.New("trim: nil PCM reader")
+	}
+	if _, err := pcm.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	fr, err := wf.FrameReader()
+	if err != nil {
+		return nil, err
+	}
+	nchans := int(wf.Fmt.NumChans)
+	p := make([]float64, block*int64(nchans))
+	sq := make([]float64, nchans)
+
+	var (
+		ranges []Range
+		pos    int64
+		start  int64 = -1 // start of the current silence
+	)
+	end := func(at int64) {
+		if start >= 0 && at-start >= max(1, minLen) {
+			ranges = append(ranges, newRange(wf, start, at))
+		}
+		start = -1
+	}
+	for {
+		n, err := cwav.ReadFullFrames(fr, p, nchans)
+		if n > 0 {
+			for c := range sq {
+				sq[c] = 0
+			}
+			for i, s := range p[:n*nchans] {
+				sq[i%nchans] += s * s
+			}
+			level := math.Inf(-1) // loudest channel, dBFS
+			for _, v := range sq {
+				level = max(level, 10*math.Log10(v/float64(n)))
+			}
+			switch {
+			case start < 0 && level < enter:
+				start = pos
+			case start >= 0 && level > leave:
+				end(pos)
+			}
+			pos += int64(n)
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	end(pos)
+	return ranges, nil
+}
+
+// TrimSilence removes the leading and trailing silence of the wav file read
+// from r and writes the rest into w. Up to padding of the silence is kept at
+// each end.
+func TrimSilence(r io.ReadSeeker, padding time.Duration, opts *SilenceOptions, w io.WriteSeeker) error {
+	src, err := cwav.Decode(r)
+	if err != nil {
+		return err
+	}
+	silences, err := DetectSilence(src, opts)
+	if err != nil {
+		return err
+	}
+	total := src.NumFrames()
+	start, end := int64(0), total
+	if len(silences) > 0 {
+		if first := silences[0]; first.StartFrame == 0 {
+			start = first.EndFrame
+		}
+		if last := silences[len(silences)-1]; last.EndFrame == total {
+			end = last.StartFrame
+		}
+	}
+	if start >= end {
+		return errors.New("trim: file is silent")
+	}
+	pad := src.DurationFrames(padding)
+	start = max(0, start-pad)
+	end = min(total, end+pad)
+	return writeFrameRange(src, start, end, w, nil)
+}
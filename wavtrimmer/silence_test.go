@@ -0,0 +1,125 @@
+package wavtrimmer
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	cwav "github.com/cakturk/pkg/wav"
+)
+
+// tone returns n frames of a full scale square wave.
+func tone(n, nchans int) []float64 {
+	p := make([]float64, n*nchans)
+	for i := range p {
+		if (i/nchans)%2 == 0 {
+			p[i] = 0.5
+		} else {
+			p[i] = -0.5
+		}
+	}
+	return p
+}
+
+func concatSamples(parts ...[]float64) []float64 {
+	var p []float64
+	for _, part := range parts {
+		p = append(p, part...)
+	}
+	return p
+}
+
+func TestDetectSilence(t *testing.T) {
+	// 1kHz: 100ms silence, 50ms tone, 30ms silence, 100ms tone, 200ms silence
+	samples := concatSamples(
+		make([]float64, 100), tone(50, 1), make([]float64, 30),
+		tone(100, 1), make([]float64, 200),
+	)
+	f := makeWav(t, 1000, 1, 16, samples)
+	wf, err := cwav.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := DetectSilence(wf, &SilenceOptions{MinDuration: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Range{
+		newRange(wf, 0, 100),
+		newRange(wf, 280, 480),
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("got %+v, want %+v", got[i], want[i])
+		}
+	}
+	if got[1].Start != 280*time.Millisecond {
+		t.Errorf("Start = %s, want 280ms", got[1].Start)
+	}
+}
+
+func TestDetectSilenceHysteresis(t *testing.T) {
+	quiet := make([]float64, 100)
+	for i := range quiet {
+		quiet[i] = 0.002 // about -54 dBFS
+	}
+	hum := make([]float64, 100)
+	for i := range hum {
+		hum[i] = 0.004 // about -48 dBFS
+	}
+	samples := concatSamples(quiet, hum, quiet, tone(100, 1))
+	f := makeWav(t, 1000, 1, 16, samples)
+	wf, err := cwav.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := DetectSilence(wf, &SilenceOptions{Hysteresis: 6})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].StartFrame != 0 || got[0].EndFrame != 300 {
+		t.Errorf("got %+v, want a single silence over [0, 300)", got)
+	}
+}
+
+func TestTrimSilence(t *testing.T) {
+	samples := concatSamples(make([]float64, 2*300), tone(200, 2), make([]float64, 2*500))
+	in := makeWav(t, 1000, 2, 24, samples)
+	out := tempFile(t)
+	if err := TrimSilence(in, 20*time.Millisecond, nil, out); err != nil {
+		t.Fatal(err)
+	}
+	wf, got := readWav(t, out)
+	if wf.Fmt.BitsPerSample != 24 || wf.Fmt.NumChans != 2 {
+		t.Errorf("unexpected format: %+v", wf.Fmt)
+	}
+	want := concatSamples(make([]float64, 2*20), tone(200, 2), make([]float64, 2*20))
+	if len(got) != len(want) {
+		t.Fatalf("got %d frames, want %d", len(got)/2, len(want)/2)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("sample %d: got %v, want %v", i, got[i], want[i])
+		}
+	}
+
+	silent := makeWav(t, 1000, 1, 16, make([]float64, 100))
+	if err := TrimSilence(silent, 0, nil, tempFile(t)); err == nil {
+		t.Error("expected an error for a silent file")
+	}
+}
+
+func TestDetectSilenceNoReaderAt(t *testing.T) {
+	in := makeWav(t, 1000, 1, 16, tone(100, 1))
+	// hide io.ReaderAt, which leaves the file without a PCM reader
+	wf, err := cwav.Decode(struct{ io.ReadSeeker }{in})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := DetectSilence(wf, nil); err == nil {
+		t.Error("got no error")
+	}
+}
@@ -0,0 +1,144 @@
+package wavtrimmer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	cwav "github.com/cakturk/pkg/wav"
+)
+
+// SplitOptions controls Split.
+type SplitOptions struct {
+	Silence SilenceOptions
+
+	// MinLength and MaxLength bound the length of the segments. Gaps
+	// which would produce a segment shorter than MinLength are skipped.
+	// A segment without any gap to cut at is cut at MaxLength. Zero
+	// means no limit.
+	MinLength time.Duration
+	MaxLength time.Duration
+
+	// Name is a text/template producing the path of each segment. It is
+	// executed with a SplitSegment, e.g. "out/utt-{{printf \"%04d\" .Index}}.wav".
+	Name string
+	// Create opens the output of each segment; os.Create if nil.
+	Create func(name string) (io.WriteSeeker, error)
+}
+
+// SplitSegment describes a segment written by Split. Index starts at 1.
+type SplitSegment struct {
+	Index int    `json:"index"`
+	Name  string `json:"name"`
+	Range
+}
+
+// Split cuts the wav file read from r into segments in the middle of its
+// silent gaps and writes each segment into a new file. Half of the leading
+// and trailing silence is dropped. It returns the manifest of the segments
+// with their positions in the source. opts must give at least Name.
+func Split(r io.ReadSeeker, opts *SplitOptions) ([]SplitSegment, error) {
+	if opts == nil {
+		return nil, errors.New("split: nil options")
+	}
+	if opts.Name == "" {
+		return nil, errors.New("split: empty name template")
+	}
+	tmpl, err := template.New("name").Parse(opts.Name)
+	if err != nil {
+		return nil, fmt.Errorf("split: %w", err)
+	}
+	create := opts.Create
+	if create == nil {
+		create = func(name string) (io.WriteSeeker, error) {
+			return os.Create(name)
+		}
+	}
+	src, err := cwav.Decode(r)
+	if err != nil {
+		return nil, err
+	}
+	silences, err := DetectSilence(src, &opts.Silence)
+	if err != nil {
+		return nil, err
+	}
+	ranges := splitRanges(
+		silences,
+		src.NumFrames(),
+		src.DurationFrames(opts.MinLength),
+		src.DurationFrames(opts.MaxLength),
+	)
+
+	var segs []SplitSegment
+	for i, rg := range ranges {
+		seg := SplitSegment{Index: i + 1, Range: newRange(src, rg[0], rg[1])}
+		var name strings.Builder
+		if err := tmpl.Execute(&name, seg); err != nil {
+			return segs, fmt.Errorf("split: %w", err)
+		}
+		seg.Name = name.String()
+		w, err := create(seg.Name)
+		if err != nil {
+			return segs, err
+		}
+		err = writeFrameRange(src, rg[0], rg[1], w, nil)
+		if c, ok := w.(io.Closer); ok {
+			if cerr := c.Close(); err == nil {
+				err = cerr
+			}
+		}
+		if err != nil {
+			return segs, err
+		}
+		segs = append(segs, seg)
+	}
+	return segs, nil
+}
+
+// splitRanges returns the segments [start, end) of a file of total frames
+// cut in the middle of the given silences.
+func splitRanges(silences []Range, total, minLen, maxLen int64) [][2]int64 {
+	start, end := int64(0), total
+	var cuts []int64
+	for _, s := range silences {
+		mid := s.StartFrame + s.Len()/2
+		switch {
+		case s.StartFrame == 0:
+			start = mid
+		case s.EndFrame == total:
+			end = mid
+		default:
+			cuts = append(cuts, mid)
+		}
+	}
+	if start >= end {
+		return nil
+	}
+
+	var out [][2]int64
+	for s := start; s < end; {
+		next := end
+		if maxLen > 0 && end-s > maxLen {
+			next = s + maxLen
+		}
+		for _, c := range cuts {
+			if c-s >= minLen && c > s && c <= next {
+				next = c
+				break
+			}
+		}
+		out = append(out, [2]int64{s, next})
+		s = next
+	}
+	// merge a short last segment into the previous one if that fits
+	if n := len(out); n > 1 && out[n-1][1]-out[n-1][0] < minLen &&
+		(maxLen == 0 || out[n-1][1]-out[n-2][0] <= maxLen) {
+		out[n-2][1] = out[n-1][1]
+		out = out[:n-1]
+	}
+	return out
+}
@@ -0,0 +1,106 @@
+package wavtrimmer
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSplit(t *testing.T) {
+	// 1kHz: utterances of 200, 50 and 300ms separated by 100ms pauses
+	samples := concatSamples(
+		make([]float64, 60), tone(200, 1), make([]float64, 100),
+		tone(50, 1), make([]float64, 100), tone(300, 1), make([]float64, 60),
+	)
+	in := makeWav(t, 1000, 1, 16, samples)
+	dir := t.TempDir()
+	segs, err := Split(in, &SplitOptions{
+		Silence:   SilenceOptions{MinDuration: 50 * time.Millisecond},
+		MinLength: 200 * time.Millisecond,
+		Name:      filepath.Join(dir, `utt-{{printf "%02d" .Index}}.wav`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The 50ms utterance is merged into the first one.
+	want := [][2]int64{{30, 310}, {310, 840}}
+	if len(segs) != len(want) {
+		t.Fatalf("got %+v, want %v", segs, want)
+	}
+	for i, seg := range segs {
+		if seg.StartFrame != want[i][0] || seg.EndFrame != want[i][1] {
+			t.Errorf("segment %d: got [%d, %d), want %v", i, seg.StartFrame, seg.EndFrame, want[i])
+		}
+		name := filepath.Join(dir, []string{"utt-01.wav", "utt-02.wav"}[i])
+		if seg.Name != name {
+			t.Errorf("Name = %q, want %q", seg.Name, name)
+		}
+		f, err := os.Open(seg.Name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, got := readWav(t, f)
+		f.Close()
+		if int64(len(got)) != seg.Len() {
+			t.Errorf("%s: got %d frames, want %d", seg.Name, len(got), seg.Len())
+		}
+		for j := range got {
+			if got[j] != samples[seg.StartFrame+int64(j)] {
+				t.Fatalf("%s: frame %d differs from the source", seg.Name, j)
+			}
+		}
+	}
+	b, err := json.Marshal(segs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var manifest []SplitSegment
+	if err := json.Unmarshal(b, &manifest); err != nil {
+		t.Fatal(err)
+	}
+	if manifest[1] != segs[1] || manifest[1].Start != 310*time.Millisecond {
+		t.Errorf("manifest did not round trip: %s", b)
+	}
+}
+
+func TestSplitErrors(t *testing.T) {
+	for _, opts := range []*SplitOptions{nil, {}, {Name: "{{"}} {
+		in := makeWav(t, 1000, 1, 16, tone(100, 1))
+		if _, err := Split(in, opts); err == nil {
+			t.Errorf("Split(%+v) succeeded", opts)
+		}
+	}
+}
+
+func TestSplitRanges(t *testing.T) {
+	silences := []Range{
+		{StartFrame: 0, EndFrame: 10},
+		{StartFrame: 100, EndFrame: 110},
+		{StartFrame: 130, EndFrame: 140},
+		{StartFrame: 490, EndFrame: 500},
+	}
+	tests := []struct {
+		min, max int64
+		want     [][2]int64
+	}{
+		{0, 0, [][2]int64{{5, 105}, {105, 135}, {135, 495}}},
+		{50, 0, [][2]int64{{5, 105}, {105, 495}}},
+		{0, 200, [][2]int64{{5, 105}, {105, 135}, {135, 335}, {335, 495}}},
+		{100, 300, [][2]int64{{5, 105}, {105, 405}, {405, 495}}},
+	}
+	for _, tt := range tests {
+		got := splitRanges(silences, 500, tt.min, tt.max)
+		if len(got) != len(tt.want) {
+			t.Errorf("min %d, max %d: got %v, want %v", tt.min, tt.max, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("min %d, max %d: got %v, want %v", tt.min, tt.max, got, tt.want)
+				break
+			}
+		}
+	}
+}
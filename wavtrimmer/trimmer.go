@@ -11,175 +11,275 @@ import (
 	"github.com/go-audio/wav"
 )
 
-// Trim function cuts samples between the specified time interval (start, end]
-// in a wav file and creates a new wave file with these audio samples.
+// Trim function cuts the samples between start and end out of a wav file
+// and creates a new wave file with these audio samples. The cut is sample
+// accurate: the output starts with the frame playing at start and stops
+// right before the frame playing at end, that is it holds the frames
+// [start*rate, end*rate) with both positions rounded down. -1 stands for the
+// beginning of the file as start and for its end as end. The INFO fields
+// known to go-audio are copied.
 func Trim(r io.ReadSeeker, start time.Duration, end time.Duration, w io.WriteSeeker) error {
-	dec := wav.NewDecoder(r)
-	if !dec.IsValidFile() {
-		return errors.New("cutter: invalid file format")
+	// go-audio rounds odd data chunk sizes up to the pad byte, so the
+	// frames are counted on the header as decoded by cwav.
+	hdr, err := cwav.Decode(r)
+	if err != nil {
+		return err
 	}
-	dec.ReadInfo()
-	t, err := dec.Duration()
+	first, last, err := durationRange(start, end, hdr)
 	if err != nil {
 		return err
 	}
-	if start == -1 {
-		start = 0
+	if first, last, err = frameRange(first, last, hdr.NumFrames()); err != nil {
+		return err
 	}
-	if end == -1 {
-		end = t
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return err
 	}
-	if t < start || t < end {
-		return fmt.Errorf("cutter: start: %s or end: %s not between 0 - %s", start, end, t)
+	dec := wav.NewDecoder(r)
+	if !dec.IsValidFile() {
+		return errors.New("trim: invalid file format")
 	}
-	if start >= end {
-		return fmt.Errorf("cutter: start: %s earlier than end: %s", start, end)
+	dec.ReadInfo()
+	if err := dec.FwdToPCM(); err != nil {
+		return err
 	}
+	nchans := int(dec.NumChans)
 	enc := wav.NewEncoder(
 		w,
 		int(dec.SampleRate),
 		int(dec.BitDepth),
-		int(dec.NumChans),
+		nchans,
 		int(dec.WavAudioFormat),
 	)
+	enc.Metadata = goaudioMetadata(hdr.List)
 	buf := &audio.IntBuffer{
-		Data: make([]int, 32*1024),
+		Data: make([]int, 32*1024-32*1024%nchans),
 		Format: &audio.Format{
-			NumChannels: int(dec.NumChans),
+			NumChannels: nchans,
 			SampleRate:  int(dec.SampleRate),
 		},
 		SourceBitDepth: int(dec.BitDepth),
 	}
-	return cutOut(start, end, buf, dec, enc)
-}
-
-func howMany(d time.Duration, dec *wav.Decoder, buf *audio.IntBuffer) (n int, remainder int) {
-	bytesInSample := dec.BitDepth / 8
-	bytesToSkip := uint64(dec.AvgBytesPerSec) * uint64(d/time.Second)
-	nrSamples := int(bytesToSkip / uint64(bytesInSample))
-	return nrSamples / len(buf.Data), nrSamples % len(buf.Data)
+	if err := cutOut(first*int64(nchans), buf, dec, nil); err != nil {
+		return err
+	}
+	if err := cutOut((last-first)*int64(nchans), buf, dec, enc); err != nil {
+		return err
+	}
+	return enc.Close()
 }
 
-func discard(to time.Duration, buf *audio.IntBuffer, dec *wav.Decoder) error {
-	var err error
-	n, remainder := howMany(to, dec, buf)
-	for n > 0 {
-		_, err = dec.PCMBuffer(buf)
+// cutOut reads the given number of samples from dec and writes them to enc,
+// or discards them if enc is nil.
+func cutOut(samples int64, buf *audio.IntBuffer, dec *wav.Decoder, enc *wav.Encoder) error {
+	data := buf.Data
+	defer func() { buf.Data = data }()
+	for samples > 0 {
+		buf.Data = data[:min(int64(len(data)), samples)]
+		n, err := dec.PCMBuffer(buf)
 		if err != nil {
 			return err
 		}
-		n--
+		if n == 0 {
+			return io.ErrUnexpectedEOF
+		}
+		buf.Data = buf.Data[:n]
+		if enc != nil {
+			if err := enc.Write(buf); err != nil {
+				return err
+			}
+		}
+		samples -= int64(n)
 	}
-	buf.Data = buf.Data[:remainder]
-	_, err = dec.PCMBuffer(buf)
+	return nil
+}
+
+// Trim2 function cuts the samples between start and end out of a wav file
+// and creates a new wave file with these audio samples. Its boundaries are
+// the same as Trim's.
+//
+// Note: This function is a slightly faster version of the original "Trim"
+// function.
+func Trim2(r io.ReadSeeker, start time.Duration, end time.Duration, w io.WriteSeeker) error {
+	return TrimWithOptions(r, start, end, w, nil)
+}
+
+// TrimFrames cuts the frames [start, end) out of a wav file and creates a
+// new wave file with them. -1 stands for the first frame as start and for
+// the end of the file as end.
+func TrimFrames(r io.ReadSeeker, start, end int64, w io.WriteSeeker) error {
+	return TrimFramesWithOptions(r, start, end, w, nil)
+}
+
+// TrimOptions controls TrimWithOptions and TrimFramesWithOptions. The zero
+// value copies the samples unchanged.
+type TrimOptions struct {
+	// Snap moves each cut to the nearest zero crossing no further than
+	// Snap away. A cut without a zero crossing nearby stays in place, as
+	// do the start and the end of the source.
+	Snap time.Duration
+	// FadeIn and FadeOut are the lengths of the fades applied at the
+	// start and the end of the output, using Curve.
+	FadeIn  time.Duration
+	FadeOut time.Duration
+	Curve   Curve
+
+	// NoMetadata drops the LIST/INFO and cue chunks of the source. By
+	// default they are copied; cue points outside the output are dropped
+	// and the others are moved along with the audio.
+	NoMetadata bool
+	// Derived, if set, returns INFO fields to add to the output given the
+	// source and the frame range [start, end) written. They replace the
+	// fields of the source with the same ID. See TrimmedFrom.
+	Derived func(src *cwav.WavFile, start, end int64) []cwav.InfoChunk
+
+	// Pad allows the range to run past the end of the source, filling
+	// the missing frames with silence. See TrimPadded.
+	Pad PadMode
+}
+
+// TrimWithOptions is like Trim2 but with options.
+func TrimWithOptions(r io.ReadSeeker, start, end time.Duration, w io.WriteSeeker, opts *TrimOptions) error {
+	_, err := TrimPadded(r, start, end, w, opts)
 	return err
 }
 
-func cutOut(
-	start, end time.Duration,
-	buf *audio.IntBuffer,
-	dec *wav.Decoder,
-	enc *wav.Encoder,
-) error {
-	var err error
-	err = discard(start, buf, dec)
-	if err != nil {
-		return err
+// TrimFramesWithOptions is like TrimFrames but with options.
+func TrimFramesWithOptions(r io.ReadSeeker, start, end int64, w io.WriteSeeker, opts *TrimOptions) error {
+	_, err := TrimFramesPadded(r, start, end, w, opts)
+	return err
+}
+
+func trimDecoded(src *cwav.WavFile, start, end int64, w io.WriteSeeker, opts *TrimOptions) (Padding, error) {
+	if opts == nil {
+		opts = &TrimOptions{}
 	}
-	// grow buf.Data to its capacity by slicing it again
-	buf.Data = buf.Data[:cap(buf.Data)]
-	n, remainder := howMany(end-start, dec, buf)
-	for n > 0 {
-		_, err = dec.PCMBuffer(buf)
-		if err != nil {
-			return err
-		}
-		err = enc.Write(buf)
-		if err != nil {
-			return err
-		}
-		n--
+	if opts.Snap < 0 || opts.FadeIn < 0 || opts.FadeOut < 0 {
+		return Padding{}, errors.New("trim: negative snap window or fade")
 	}
-	buf.Data = buf.Data[:remainder]
-	_, err = dec.PCMBuffer(buf)
-	if err != nil {
-		return err
+	if opts.Curve < Linear || opts.Curve > Logarithmic {
+		return Padding{}, fmt.Errorf("trim: unknown fade curve: %d", opts.Curve)
+	}
+	var (
+		first, last int64
+		pad         Padding
+		err         error
+	)
+	if opts.Pad == NoPad {
+		first, last, err = frameRange(start, end, src.NumFrames())
+	} else {
+		first, last, pad, err = padRange(start, end, src.NumFrames(), opts.Pad)
 	}
-	err = enc.Write(buf)
 	if err != nil {
-		return err
+		return Padding{}, err
 	}
-	return enc.Close()
+	if window := src.DurationFrames(opts.Snap); window > 0 && first < last {
+		if first, last, err = snapRange(src, first, last, window); err != nil {
+			return Padding{}, err
+		}
+	}
+	return pad, writePadded(src, first, last, pad, w, opts)
 }
 
-func durationReader(src *cwav.WavFile, start, end time.Duration) (io.Reader, error) {
-	off := int64(float64(src.Fmt.ByteRate) * float64(start) / float64(time.Second))
-	if off%2 != 0 {
-		off++
+// durationRange validates the time interval [start, end) and converts it
+// into frames of wf, rounding down. -1 is kept as is.
+func durationRange(start, end time.Duration, wf *cwav.WavFile) (int64, int64, error) {
+	if start < -1 || end < -1 {
+		return 0, 0, fmt.Errorf("trim: negative start: %s or end: %s", start, end)
 	}
-	r := src.Data.PCMReader()
-	if _, err := r.Seek(off, io.SeekCurrent); err != nil {
-		return nil, err
+	if start != -1 && end != -1 && start >= end {
+		return 0, 0, fmt.Errorf("trim: start: %s not earlier than end: %s", start, end)
 	}
-	count := int64(float64(src.Fmt.ByteRate) * float64(end-start) / float64(time.Second))
-	if count%2 != 0 {
-		count--
+	first, last := int64(-1), int64(-1)
+	if start != -1 {
+		first = wf.DurationFrames(start)
 	}
-	return io.LimitReader(r, count), nil
-}
-
-// Trim2 function cuts samples between the specified time interval (start, end]
-// in a wav file and creates a new wave file with these audio samples.
-//
-// Note: This function is a slightly faster version of the original "Trim"
-// function.
-func Trim2(r io.ReadSeeker, start time.Duration, end time.Duration, w io.WriteSeeker) error {
-	wavSrc, err := cwav.Decode(r)
-	if err != nil {
-		return err
-	}
-	src := wavSrc.Data.PCMReader()
-	if src == nil {
-		return errors.New("trim: nil PCM reader")
+	if end != -1 {
+		last = wf.DurationFrames(end)
 	}
+	return first, last, nil
+}
 
-	dur := wavSrc.Duration()
+// frameRange validates the frame range [start, end) of a file of total
+// frames, resolving -1 to either end of the file.
+func frameRange(start, end, total int64) (int64, int64, error) {
 	if start == -1 {
 		start = 0
 	}
 	if end == -1 {
-		end = dur
+		end = total
 	}
-	if dur < start || dur < end {
-		return fmt.Errorf("trim: start: %s or end: %s not between 0 - %s", start, end, dur)
+	if start < 0 || end < 0 || start > total || end > total {
+		return 0, 0, fmt.Errorf("trim: start frame: %d or end frame: %d not between 0 - %d", start, end, total)
 	}
 	if start >= end {
-		return fmt.Errorf("trim: start: %s earlier than end: %s", start, end)
+		return 0, 0, fmt.Errorf("trim: start frame: %d not earlier than end frame: %d", start, end)
 	}
+	return start, end, nil
+}
+
+// writeFrameRange writes the frames [start, end) of src into a new wav file,
+// applying the fades and metadata options of opts, which may be nil.
+func writeFrameRange(src *cwav.WavFile, start, end int64, w io.WriteSeeker, opts *TrimOptions) error {
+	return writePadded(src, start, end, Padding{}, w, opts)
+}
 
-	srcDr, err := durationReader(wavSrc, start, end)
+// writePadded is like writeFrameRange but surrounds the frames with the
+// silence of pad. Fades apply to the frames of src only.
+func writePadded(src *cwav.WavFile, start, end int64, pad Padding, w io.WriteSeeker, opts *TrimOptions) error {
+	if opts == nil {
+		opts = &TrimOptions{}
+	}
+	wavDst, err := cwav.CreateFmt(w, src.Fmt)
 	if err != nil {
 		return err
 	}
-
-	sampleRate := int(wavSrc.Fmt.SampleRate)
-	bitsPerSample := int(wavSrc.Fmt.BitsPerSample)
-	channels := int(wavSrc.Fmt.NumChans)
-	wavDst, err := cwav.Create(w, sampleRate, channels, bitsPerSample)
+	fw, err := wavDst.FrameWriter()
 	if err != nil {
 		return err
 	}
-	dst := wavDst.Data.PCMWriter()
-	if dst == nil {
-		return errors.New("trim: nil PCM writer")
+	nchans := int(src.Fmt.NumChans)
+	if err := writeSilence(fw, pad.Start, nchans); err != nil {
+		return err
 	}
-
-	p := make([]byte, 128*1024)
-	if _, err := io.CopyBuffer(dst, srcDr, p); err != nil {
+	fadeIn, fadeOut := src.DurationFrames(opts.FadeIn), src.DurationFrames(opts.FadeOut)
+	if fadeIn > 0 || fadeOut > 0 {
+		err = writeFaded(wavDst, src, start, end, fadeIn, fadeOut, opts.Curve)
+	} else {
+		err = copyFrameRange(wavDst, src, start, end)
+	}
+	if err != nil {
 		return err
 	}
-	if _, err := wavDst.Encode(w); err != nil {
+	if err := writeSilence(fw, pad.End, nchans); err != nil {
 		return err
 	}
-	return nil
+	setMetadata(wavDst, src, start, end, opts)
+	if c := wavDst.Cue; c != nil && pad.Start > 0 {
+		for i := range c.Points {
+			c.Points[i].SampleOffset += uint32(pad.Start)
+			c.Points[i].Position = c.Points[i].SampleOffset
+		}
+	}
+	_, err = wavDst.Encode(w)
+	return err
+}
+
+// copyFrameRange copies the frames [start, end) of src into dst unchanged.
+func copyFrameRange(wavDst, src *cwav.WavFile, start, end int64) error {
+	pcm := src.Data.PCMReader()
+	if pcm == nil {
+		return errors.New("trim: nil PCM reader")
+	}
+	blockAlign := int64(src.Fmt.BlockAlign)
+	if _, err := pcm.Seek(start*blockAlign, io.SeekStart); err != nil {
+		return err
+	}
+	dst := wavDst.Data.PCMWriter()
+	if dst == nil {
+		return errors.New("trim: nil PCM writer")
+	}
+	p := make([]byte, 128*1024)
+	_, err := io.CopyBuffer(dst, io.LimitReader(pcm, (end-start)*blockAlign), p)
+	return err
 }
@@ -75,53 +75,167 @@ func TestTrim(t *testing.T) {
 }
 
 func TestTrim2(t *testing.T) {
-	in, err := os.Open("/tmp/sil/mother_talk.wav")
+	in, err := os.Open("testdata/a2002011001-e02-16kHz.wav")
 	if err != nil {
 		t.Fatal(err)
 	}
 	defer in.Close()
-	out, err := os.Create("/tmp/sil/cropped.wav")
-	if err != nil {
-		t.Fatal(err)
-	}
-	defer out.Close()
+	out := tempFile(t)
 	if err := Trim2(in, 220*time.Millisecond, 500*time.Millisecond, out); err != nil {
 		t.Fatal(err)
 	}
+	wf, _ := readWav(t, out)
+	if n := wf.NumFrames(); n != 4480 {
+		t.Errorf("got %d frames, want 4480", n)
+	}
 }
 
-func BenchmarkTrim(b *testing.B) {
-	in, err := os.Open("/tmp/sil/001-qq2.wav")
-	if err != nil {
-		b.Fatal(err)
+// frameSamples returns frames frames whose samples are all distinct within a
+// frame and exactly representable at 8 bits.
+func frameSamples(frames, nchans int) []float64 {
+	p := make([]float64, frames*nchans)
+	for i := range p {
+		p[i] = float64(i%255-127) / 128
 	}
-	defer in.Close()
-	out, err := os.Create("testdata/cropped-old.wav")
-	if err != nil {
-		b.Fatal(err)
+	return p
+}
+
+func TestTrimSampleAccurate(t *testing.T) {
+	const rate = 8000
+	ranges := []struct {
+		start, end  time.Duration
+		first, last int
+	}{
+		{1500 * time.Microsecond, 3 * time.Millisecond, 12, 24},
+		// 1.5s used to be truncated to 1s
+		{1500 * time.Millisecond, 1600 * time.Millisecond, 12000, 12800},
+		{125*time.Microsecond - 1, 250 * time.Microsecond, 0, 2},
+		{-1, 250*time.Microsecond + 1, 0, 2},
+		{2 * time.Second, -1, 16000, 16001},
+		{-1, -1, 0, 16001},
 	}
-	defer out.Close()
-	err = Trim(in, 3*time.Hour, 4*time.Hour, out)
-	if err != nil {
-		b.Fatalf("Crop(...) failed: %v", err)
+	trims := []struct {
+		name string
+		fn   func(io.ReadSeeker, time.Duration, time.Duration, io.WriteSeeker) error
+	}{
+		{"Trim", Trim},
+		{"Trim2", Trim2},
+	}
+	for _, nbits := range []int{8, 16, 24, 32} {
+		for nchans := 1; nchans <= 8; nchans++ {
+			src := frameSamples(16001, nchans)
+			in := makeWav(t, rate, nchans, nbits, src)
+			for _, tr := range trims {
+				for _, rg := range ranges {
+					if _, err := in.Seek(0, io.SeekStart); err != nil {
+						t.Fatal(err)
+					}
+					out := tempFile(t)
+					if err := tr.fn(in, rg.start, rg.end, out); err != nil {
+						t.Fatalf("%s(%d-bit, %d chans, %s, %s): %v", tr.name, nbits, nchans, rg.start, rg.end, err)
+					}
+					wf, got := readWav(t, out)
+					want := src[rg.first*nchans : rg.last*nchans]
+					if wf.Fmt.BitsPerSample != uint16(nbits) || wf.Fmt.NumChans != uint16(nchans) {
+						t.Errorf("%s: fmt = %+v", tr.name, wf.Fmt)
+					}
+					if !equalSamples(got, want) {
+						t.Errorf("%s(%d-bit, %d chans, %s, %s): got %d samples, want frames [%d, %d)",
+							tr.name, nbits, nchans, rg.start, rg.end, len(got), rg.first, rg.last)
+					}
+				}
+			}
+		}
 	}
 }
 
-func BenchmarkTrim2(b *testing.B) {
+func TestTrimFrames(t *testing.T) {
+	src := frameSamples(1000, 3)
+	in := makeWav(t, 8000, 3, 24, src)
+	tests := []struct {
+		start, end  int64
+		first, last int
+	}{
+		{0, 1, 0, 1},
+		{-1, 10, 0, 10},
+		{997, -1, 997, 1000},
+		{-1, -1, 0, 1000},
+		{123, 457, 123, 457},
+	}
+	for _, tt := range tests {
+		in.Seek(0, io.SeekStart)
+		out := tempFile(t)
+		if err := TrimFrames(in, tt.start, tt.end, out); err != nil {
+			t.Fatalf("TrimFrames(%d, %d): %v", tt.start, tt.end, err)
+		}
+		_, got := readWav(t, out)
+		if !equalSamples(got, src[tt.first*3:tt.last*3]) {
+			t.Errorf("TrimFrames(%d, %d): got %d frames, want [%d, %d)", tt.start, tt.end, len(got)/3, tt.first, tt.last)
+		}
+	}
+	for _, rg := range [][2]int64{{5, 5}, {6, 5}, {-2, 5}, {0, 1001}, {1000, -1}} {
+		in.Seek(0, io.SeekStart)
+		if err := TrimFrames(in, rg[0], rg[1], tempFile(t)); err == nil {
+			t.Errorf("TrimFrames(%d, %d) succeeded", rg[0], rg[1])
+		}
+	}
+}
+
+func equalSamples(a, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestTrimErrors(t *testing.T) {
+	in := makeWav(t, 1000, 2, 16, frameSamples(100, 2))
+	tests := []struct {
+		start, end time.Duration
+	}{
+		{50 * time.Millisecond, 20 * time.Millisecond},
+		{0, 101 * time.Millisecond},
+		{101 * time.Millisecond, -1},
+		{-2 * time.Millisecond, -1},
+		{10 * time.Millisecond, 10*time.Millisecond + time.Microsecond}, // same frame
+	}
+	for _, tt := range tests {
+		for _, fn := range []func(io.ReadSeeker, time.Duration, time.Duration, io.WriteSeeker) error{Trim, Trim2} {
+			in.Seek(0, io.SeekStart)
+			if err := fn(in, tt.start, tt.end, tempFile(t)); err == nil {
+				t.Errorf("trim(%s, %s) succeeded", tt.start, tt.end)
+			}
+		}
+	}
+}
+
+func BenchmarkTrim(b *testing.B) {
 	for i := 0; i < b.N; i++ {
-		in, err := os.Open("/tmp/sil/001-qq2.wav")
+		in, err := os.Open("testdata/a2002011001-e02-16kHz.wav")
 		if err != nil {
 			b.Fatal(err)
 		}
-		defer in.Close()
-		out, err := os.Create("/tmp/sil/cropped.wav")
+		if err := Trim(in, 3*time.Second, 40*time.Second, tempFile(b)); err != nil {
+			b.Fatalf("Crop(...) failed: %v", err)
+		}
+		in.Close()
+	}
+}
+
+func BenchmarkTrim2(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		in, err := os.Open("testdata/a2002011001-e02-16kHz.wav")
 		if err != nil {
 			b.Fatal(err)
 		}
-		defer out.Close()
-		if err := Trim2(in, 3*time.Hour, 4*time.Hour, out); err != nil {
+		if err := Trim2(in, 3*time.Second, 40*time.Second, tempFile(b)); err != nil {
 			b.Fatal(err)
 		}
-
+		in.Close()
 	}
 }
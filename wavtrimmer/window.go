@@ -0,0 +1,229 @@
+package wavtrimmer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	cwav "github.com/cakturk/pkg/wav"
+)
+
+// LastWindow is the policy for a final window running past the end of the
+// source.
+type LastWindow int
+
+const (
+	// DropLast drops the final window if it is short.
+	DropLast LastWindow = iota
+	// PadLast fills the final window up to its length with silence.
+	PadLast
+	// KeepLast keeps the final window short.
+	KeepLast
+)
+
+// WindowOptions controls Windows. Exactly one of Name and Frames must be set.
+type WindowOptions struct {
+	// Length is the length of each window. Hop is the distance between the
+	// starts of consecutive windows; it defaults to Length.
+	Length time.Duration
+	Hop    time.Duration
+	// Last decides what happens to a final window shorter than Length.
+	Last LastWindow
+
+	// Name is a text/template producing the path of each window. It is
+	// executed with a Window, e.g. "out/win-{{printf \"%06d\" .Index}}.wav".
+	Name string
+	// Create opens the output of each window; os.Create if nil. Outputs
+	// implementing io.Closer are closed once written.
+	Create func(name string) (io.WriteSeeker, error)
+
+	// Frames, if set, is called with the interleaved samples of each
+	// window instead of writing files. The slice is reused between calls.
+	// An error returned by Frames stops Windows.
+	Frames func(win Window, samples []float64) error
+}
+
+// Window describes a window produced by Windows. Index starts at 1. Range is
+// the part of the source in the window; Padding is the number of frames of
+// silence added after it.
+type Window struct {
+	Index   int    `json:"index"`
+	Name    string `json:"name,omitempty"`
+	Padding int64  `json:"padding"`
+	Range
+}
+
+// Windows cuts the wav file read from r into windows of a fixed length
+// starting every hop, and either writes each window into a new file or passes
+// its samples to a callback. Windows stop once one reaches the end of the
+// source, so the source is covered without a trailing window lying entirely
+// within the previous one. It returns the manifest of the windows.
+func Windows(r io.ReadSeeker, opts *WindowOptions) ([]Window, error) {
+	if opts == nil || (opts.Name == "") == (opts.Frames == nil) {
+		return nil, errors.New("window: need exactly one of name template and frames callback")
+	}
+	if opts.Length <= 0 || opts.Hop < 0 {
+		return nil, fmt.Errorf("window: invalid length: %s or hop: %s", opts.Length, opts.Hop)
+	}
+	if opts.Last < DropLast || opts.Last > KeepLast {
+		return nil, fmt.Errorf("window: unknown last window policy: %d", opts.Last)
+	}
+	var tmpl *template.Template
+	if opts.Name != "" {
+		var err error
+		if tmpl, err = template.New("name").Parse(opts.Name); err != nil {
+			return nil, fmt.Errorf("window: %w", err)
+		}
+	}
+	create := opts.Create
+	if create == nil {
+		create = func(name string) (io.WriteSeeker, error) {
+			return os.Create(name)
+		}
+	}
+	src, err := cwav.Decode(r)
+	if err != nil {
+		return nil, err
+	}
+	length := src.DurationFrames(opts.Length)
+	hop := length
+	if opts.Hop > 0 {
+		hop = src.DurationFrames(opts.Hop)
+	}
+	if length == 0 || hop == 0 {
+		return nil, fmt.Errorf("window: length: %s or hop: %s shorter than a frame", opts.Length, opts.Hop)
+	}
+
+	wr, err := newWindowReader(src, length)
+	if err != nil {
+		return nil, err
+	}
+	total := src.NumFrames()
+	var wins []Window
+	for start := int64(0); start < total; start += hop {
+		end := min(start+length, total)
+		if end-start < length && opts.Last == DropLast {
+			break
+		}
+		win := Window{Index: len(wins) + 1, Range: newRange(src, start, end)}
+		if opts.Last == PadLast {
+			win.Padding = length - win.Len()
+		}
+		samples, err := wr.window(start, end, win.Padding)
+		if err != nil {
+			return wins, err
+		}
+		if opts.Frames != nil {
+			err = opts.Frames(win, samples)
+		} else {
+			err = writeWindow(src, &win, samples, tmpl, create)
+		}
+		if err != nil {
+			return wins, err
+		}
+		wins = append(wins, win)
+		if end == total {
+			break
+		}
+	}
+	return wins, nil
+}
+
+// writeWindow writes the samples of win into a new file named after tmpl.
+func writeWindow(src *cwav.WavFile, win *Window, samples []float64, tmpl *template.Template, create func(string) (io.WriteSeeker, error)) (err error) {
+	var name strings.Builder
+	if err := tmpl.Execute(&name, win); err != nil {
+		return fmt.Errorf("window: %w", err)
+	}
+	win.Name = name.String()
+	w, err := create(win.Name)
+	if err != nil {
+		return err
+	}
+	if c, ok := w.(io.Closer); ok {
+		defer func() {
+			if cerr := c.Close(); err == nil {
+				err = cerr
+			}
+		}()
+	}
+	dst, err := cwav.CreateFmt(w, src.Fmt)
+	if err != nil {
+		return err
+	}
+	fw, err := dst.FrameWriter()
+	if err != nil {
+		return err
+	}
+	if _, err := fw.WriteFrames(samples); err != nil {
+		return err
+	}
+	setMetadata(dst, src, win.StartFrame, win.EndFrame, &TrimOptions{})
+	_, err = dst.Encode(w)
+	return err
+}
+
+// windowReader reads the source in order, keeping the frames shared by
+// overlapping windows and skipping those between windows.
+type windowReader struct {
+	pcm        io.ReadSeeker
+	fr         *cwav.FrameReader
+	nchans     int
+	blockAlign int64
+	buf        []float64
+	first, pos int64 // frames [first, pos) of the source are in buf
+}
+
+func newWindowReader(src *cwav.WavFile, length int64) (*windowReader, error) {
+	pcm := src.Data.PCMReader()
+	if pcm == nil {
+		return nil, errors.New("window: nil PCM reader")
+	}
+	if _, err := pcm.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	fr, err := src.FrameReader()
+	if err != nil {
+		return nil, err
+	}
+	nchans := int(src.Fmt.NumChans)
+	return &windowReader{
+		pcm:        pcm,
+		fr:         fr,
+		nchans:     nchans,
+		blockAlign: int64(src.Fmt.BlockAlign),
+		buf:        make([]float64, length*int64(nchans)),
+	}, nil
+}
+
+// window returns the samples of the frames [start, end) followed by pad
+// frames of silence. start never decreases between calls.
+func (wr *windowReader) window(start, end, pad int64) ([]float64, error) {
+	nc := int64(wr.nchans)
+	if start < wr.pos {
+		copy(wr.buf, wr.buf[(start-wr.first)*nc:(wr.pos-wr.first)*nc])
+	} else {
+		if _, err := wr.pcm.Seek((start-wr.pos)*wr.blockAlign, io.SeekCurrent); err != nil {
+			return nil, err
+		}
+		wr.pos = start
+	}
+	wr.first = start
+	if wr.pos < end {
+		n, err := cwav.ReadFullFrames(wr.fr, wr.buf[(wr.pos-start)*nc:(end-start)*nc], wr.nchans)
+		wr.pos += int64(n)
+		if wr.pos < end {
+			if err == nil || err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+	}
+	p := wr.buf[:(end-start+pad)*nc]
+	clear(p[(end-start)*nc:])
+	return p, nil
+}
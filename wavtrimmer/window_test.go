@@ -0,0 +1,123 @@
+package wavtrimmer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func ramp(n, nchans int) []float64 {
+	p := make([]float64, n*nchans)
+	for i := range p {
+		p[i] = float64(i/nchans%127+1) / 128 // exact at any bit depth
+	}
+	return p
+}
+
+func TestWindowsFrames(t *testing.T) {
+	// 1kHz, 2 channels, 1050ms: 400ms windows every 300ms
+	samples := ramp(1050, 2)
+	tests := []struct {
+		last LastWindow
+		hop  time.Duration
+		want [][3]int64 // start, end, padding
+	}{
+		{DropLast, 300 * time.Millisecond, [][3]int64{{0, 400, 0}, {300, 700, 0}, {600, 1000, 0}}},
+		{PadLast, 300 * time.Millisecond, [][3]int64{{0, 400, 0}, {300, 700, 0}, {600, 1000, 0}, {900, 1050, 250}}},
+		{KeepLast, 300 * time.Millisecond, [][3]int64{{0, 400, 0}, {300, 700, 0}, {600, 1000, 0}, {900, 1050, 0}}},
+		// gaps between windows
+		{KeepLast, 500 * time.Millisecond, [][3]int64{{0, 400, 0}, {500, 900, 0}, {1000, 1050, 0}}},
+		// hop defaults to the length
+		{PadLast, 0, [][3]int64{{0, 400, 0}, {400, 800, 0}, {800, 1050, 150}}},
+	}
+	for _, tt := range tests {
+		in := makeWav(t, 1000, 2, 16, samples)
+		var got [][3]int64
+		wins, err := Windows(in, &WindowOptions{
+			Length: 400 * time.Millisecond,
+			Hop:    tt.hop,
+			Last:   tt.last,
+			Frames: func(win Window, p []float64) error {
+				got = append(got, [3]int64{win.StartFrame, win.EndFrame, win.Padding})
+				if want := (win.Len() + win.Padding) * 2; int64(len(p)) != want {
+					t.Fatalf("window %d: got %d samples, want %d", win.Index, len(p), want)
+				}
+				want := make([]float64, len(p))
+				copy(want, samples[win.StartFrame*2:win.EndFrame*2])
+				if !equalSamples(p, want) {
+					t.Fatalf("window %d: samples differ from the source", win.Index)
+				}
+				return nil
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(wins) != len(tt.want) || len(got) != len(tt.want) {
+			t.Fatalf("last %d hop %s: got %v, want %v", tt.last, tt.hop, got, tt.want)
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("last %d hop %s: window %d = %v, want %v", tt.last, tt.hop, i, got[i], tt.want[i])
+			}
+			if wins[i].Index != i+1 {
+				t.Errorf("Index = %d, want %d", wins[i].Index, i+1)
+			}
+		}
+	}
+}
+
+func TestWindowsFiles(t *testing.T) {
+	samples := ramp(230, 1)
+	in := makeWav(t, 1000, 1, 8, samples)
+	dir := t.TempDir()
+	wins, err := Windows(in, &WindowOptions{
+		Length: 100 * time.Millisecond,
+		Hop:    50 * time.Millisecond,
+		Last:   PadLast,
+		Name:   filepath.Join(dir, `win-{{printf "%03d" .Index}}.wav`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(wins) != 4 {
+		t.Fatalf("got %d windows, want 4", len(wins))
+	}
+	for _, win := range wins {
+		f, err := os.Open(win.Name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, got := readWav(t, f)
+		f.Close()
+		// 8-bit silence decodes to 0
+		want := make([]float64, 100)
+		copy(want, samples[win.StartFrame:win.EndFrame])
+		if !equalSamples(got, want) {
+			t.Errorf("%s: got %v, want %v", win.Name, got, want)
+		}
+	}
+	if last := wins[3]; last.StartFrame != 150 || last.EndFrame != 230 || last.Padding != 20 {
+		t.Errorf("last window = %+v", last)
+	}
+}
+
+func TestWindowsErrors(t *testing.T) {
+	frames := func(Window, []float64) error { return nil }
+	tests := []*WindowOptions{
+		nil,
+		{Length: time.Second},
+		{Length: time.Second, Name: "x.wav", Frames: frames},
+		{Frames: frames},
+		{Length: time.Second, Hop: -1, Frames: frames},
+		{Length: time.Second, Last: KeepLast + 1, Frames: frames},
+		{Length: time.Microsecond, Frames: frames},
+	}
+	for i, opts := range tests {
+		in := makeWav(t, 1000, 1, 16, ramp(10, 1))
+		if _, err := Windows(in, opts); err == nil {
+			t.Errorf("%d: got no error", i)
+		}
+	}
+}